@@ -17,6 +17,15 @@ func main() {
 	logger := logger.New()
 
 	srv := webserver.New(config, logger)
+	if err := srv.Connect(); err != nil {
+		logger.Fatal().Err(err).Msg("error while connecting to db")
+	}
+	defer srv.Close()
+	if err := srv.StartScheduler(); err != nil {
+		logger.Fatal().Err(err).Msg("error while starting scheduler")
+	}
+	srv.StartTracker()
+
 	go func() {
 		logger.Info().Msgf("starting server at %s:%s", config.Host, config.Port)
 		if err := srv.Web.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -0,0 +1,159 @@
+package bencode
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var ErrInvalid = errors.New("invalid bencode data")
+
+// maxDepth bounds nested lists and dictionaries, .torrent files are only a few levels deep.
+const maxDepth = 64
+
+// Decode decodes bencoded data, integers are returned as int64, strings as string, lists as []any
+// and dictionaries as map[string]any.
+func Decode(data []byte) (any, error) {
+	d := &decoder{data: data}
+	value, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("%w: trailing data at %d", ErrInvalid, d.pos)
+	}
+	return value, nil
+}
+
+// DecodeDict decodes a bencoded dictionary and also returns the raw bytes of each of its values,
+// e.g. the info dictionary of a .torrent file whose sha1 is the infohash.
+func DecodeDict(data []byte) (map[string]any, map[string][]byte, error) {
+	d := &decoder{data: data, raw: map[string][]byte{}}
+	value, err := d.value()
+	if err != nil {
+		return nil, nil, err
+	}
+	dict, ok := value.(map[string]any)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: not a dictionary", ErrInvalid)
+	}
+	return dict, d.raw, nil
+}
+
+type decoder struct {
+	data  []byte
+	pos   int
+	depth int
+	// raw collects the values of the top level dictionary
+	raw map[string][]byte
+}
+
+func (d *decoder) value() (any, error) {
+	if d.pos >= len(d.data) {
+		return nil, fmt.Errorf("%w: unexpected end of data", ErrInvalid)
+	}
+	switch c := d.data[d.pos]; {
+	case c == 'i':
+		return d.integer()
+	case c == 'l':
+		return d.list()
+	case c == 'd':
+		return d.dict()
+	case c >= '0' && c <= '9':
+		return d.string()
+	default:
+		return nil, fmt.Errorf("%w: unexpected %q at %d", ErrInvalid, c, d.pos)
+	}
+}
+
+func (d *decoder) integer() (int64, error) {
+	end := d.index('e', d.pos+1)
+	if end < 0 {
+		return 0, fmt.Errorf("%w: unterminated integer at %d", ErrInvalid, d.pos)
+	}
+	n, err := strconv.ParseInt(string(d.data[d.pos+1:end]), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid integer at %d", ErrInvalid, d.pos)
+	}
+	d.pos = end + 1
+	return n, nil
+}
+
+func (d *decoder) string() (string, error) {
+	colon := d.index(':', d.pos)
+	if colon < 0 {
+		return "", fmt.Errorf("%w: invalid string at %d", ErrInvalid, d.pos)
+	}
+	length, err := strconv.Atoi(string(d.data[d.pos:colon]))
+	// compared against the remaining data so huge lengths do not overflow
+	if err != nil || length < 0 || length > len(d.data)-colon-1 {
+		return "", fmt.Errorf("%w: invalid string length at %d", ErrInvalid, d.pos)
+	}
+	d.pos = colon + 1 + length
+	return string(d.data[colon+1 : d.pos]), nil
+}
+
+func (d *decoder) list() ([]any, error) {
+	d.pos++
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.depth > maxDepth {
+		return nil, fmt.Errorf("%w: too deeply nested", ErrInvalid)
+	}
+	list := []any{}
+	for {
+		if d.pos >= len(d.data) {
+			return nil, fmt.Errorf("%w: unterminated list", ErrInvalid)
+		}
+		if d.data[d.pos] == 'e' {
+			d.pos++
+			return list, nil
+		}
+		value, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+	}
+}
+
+func (d *decoder) dict() (map[string]any, error) {
+	d.pos++
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.depth > maxDepth {
+		return nil, fmt.Errorf("%w: too deeply nested", ErrInvalid)
+	}
+	dict := map[string]any{}
+	for {
+		if d.pos >= len(d.data) {
+			return nil, fmt.Errorf("%w: unterminated dictionary", ErrInvalid)
+		}
+		if d.data[d.pos] == 'e' {
+			d.pos++
+			return dict, nil
+		}
+		key, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		start := d.pos
+		value, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		dict[key] = value
+		if d.raw != nil && d.depth == 1 {
+			d.raw[key] = d.data[start:d.pos]
+		}
+	}
+}
+
+func (d *decoder) index(c byte, from int) int {
+	for i := from; i < len(d.data); i++ {
+		if d.data[i] == c {
+			return i
+		}
+	}
+	return -1
+}
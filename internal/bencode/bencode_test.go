@@ -0,0 +1,78 @@
+package bencode
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		data string
+		want any
+	}{
+		{"i42e", int64(42)},
+		{"i-3e", int64(-3)},
+		{"4:spam", "spam"},
+		{"0:", ""},
+		{"l4:spami1ee", []any{"spam", int64(1)}},
+		{"le", []any{}},
+		{"d3:cow3:moo4:spaml1:a1:bee", map[string]any{"cow": "moo", "spam": []any{"a", "b"}}},
+	}
+	for _, tt := range tests {
+		got, err := Decode([]byte(tt.data))
+		if err != nil {
+			t.Errorf("Decode(%q) error = %v", tt.data, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Decode(%q) = %#v, want %#v", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	tests := map[string]string{
+		"empty":              "",
+		"truncated string":   "10:abc",
+		"huge length":        "9223372036854775807:abc",
+		"length overflow":    "99999999999999999999:abc",
+		"negative length":    "-1:abc",
+		"missing colon":      "3abc",
+		"unterminated int":   "i42",
+		"invalid int":        "i4x2e",
+		"empty int":          "ie",
+		"unterminated list":  "l4:spam",
+		"unterminated dict":  "d3:cow3:moo",
+		"dict without value": "d3:cowe",
+		"non string key":     "di1e3:mooe",
+		"huge key length":    "d9223372036854775807:a3:mooe",
+		"trailing data":      "i1ei2e",
+		"unexpected byte":    "x",
+		"too deeply nested":  strings.Repeat("l", maxDepth+1) + strings.Repeat("e", maxDepth+1),
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Decode([]byte(data)); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Decode(%q) error = %v, want ErrInvalid", data, err)
+			}
+			if _, _, err := DecodeDict([]byte("d4:info" + data + "e")); !errors.Is(err, ErrInvalid) {
+				t.Errorf("DecodeDict(%q) error = %v, want ErrInvalid", data, err)
+			}
+		})
+	}
+}
+
+func TestDecodeDictRaw(t *testing.T) {
+	dict, raw, err := DecodeDict([]byte("d8:announce3:url4:infod4:name4:filee1:zi1ee"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dict["announce"] != "url" {
+		t.Errorf("announce = %v", dict["announce"])
+	}
+	if string(raw["info"]) != "d4:name4:filee" {
+		t.Errorf("raw info = %q", raw["info"])
+	}
+}
@@ -0,0 +1,80 @@
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+// Breaker opens after a number of consecutive failures and rejects calls until it is reset
+// or the cooldown elapses, after the cooldown a single call is allowed to probe the upstream.
+type Breaker struct {
+	Name      string
+	threshold int
+	cooldown  time.Duration
+	mu        sync.Mutex
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+// New returns a breaker for the upstream, a threshold lower or equal than zero disables it.
+func New(name string, threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		Name:      name,
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// Allow returns ErrOpen when calls to the upstream must be short-circuited.
+func (b *Breaker) Allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openedAt.IsZero() {
+		return nil
+	}
+	if b.cooldown > 0 && time.Since(b.openedAt) >= b.cooldown && !b.probing {
+		b.probing = true
+		return nil
+	}
+	return ErrOpen
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.openedAt = time.Time{}
+	b.probing = false
+}
+
+// Failure records a failed call, it returns true when the breaker has just been opened.
+func (b *Breaker) Failure() bool {
+	if b.threshold <= 0 {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.probing {
+		// probe failed, keep it open for another cooldown
+		b.probing = false
+		b.openedAt = time.Now()
+		return false
+	}
+	if b.openedAt.IsZero() && b.failures >= b.threshold {
+		b.openedAt = time.Now()
+		return true
+	}
+	return false
+}
+
+func (b *Breaker) Reset() {
+	b.Success()
+}
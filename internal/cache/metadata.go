@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/processor-films/internal/config"
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+// Store persists the metadata so it survives restarts and is shared between replicas, GetCachedMetadata
+// returns when the metadata was fetched along with it.
+type Store interface {
+	GetCachedMetadata(ctx context.Context, infoHash string, maxAge time.Duration) (*models.TorrentMetadata, time.Time, error)
+	SaveCachedMetadata(ctx context.Context, infoHash string, metadata *models.TorrentMetadata) error
+}
+
+type item struct {
+	infoHash  string
+	metadata  *models.TorrentMetadata
+	expiresAt time.Time
+}
+
+// Metadata caches torrent metadata by infohash, an in-memory LRU is consulted before the store.
+type Metadata struct {
+	config *config.Config
+	logger *zerolog.Logger
+	store  Store
+	mu     sync.Mutex
+	items  map[string]*list.Element
+	lru    *list.List
+
+	memoryHits   atomic.Uint64
+	databaseHits atomic.Uint64
+	misses       atomic.Uint64
+}
+
+func New(config *config.Config, logger *zerolog.Logger, store Store) *Metadata {
+	return &Metadata{
+		config: config,
+		logger: logger,
+		store:  store,
+		items:  map[string]*list.Element{},
+		lru:    list.New(),
+	}
+}
+
+// Get returns the cached metadata of the infohash, false when it is missing or expired.
+// Store failures are logged and reported as misses.
+func (m *Metadata) Get(ctx context.Context, infoHash string) (*models.TorrentMetadata, bool) {
+	if m.config.MetadataCache.Ttl <= 0 {
+		return nil, false
+	}
+	infoHash = strings.ToLower(infoHash)
+	if metadata, ok := m.memory(infoHash); ok {
+		m.memoryHits.Add(1)
+		return metadata, true
+	}
+	metadata, fetchedAt, err := m.store.GetCachedMetadata(ctx, infoHash, m.config.MetadataCache.Ttl)
+	if err != nil {
+		m.logger.Err(err).Msgf("error while reading cached metadata for %s", infoHash)
+	}
+	if metadata == nil {
+		m.misses.Add(1)
+		return nil, false
+	}
+	m.databaseHits.Add(1)
+	m.remember(infoHash, metadata, fetchedAt)
+	return metadata, true
+}
+
+// Set caches the metadata of the infohash.
+func (m *Metadata) Set(ctx context.Context, infoHash string, metadata *models.TorrentMetadata) {
+	if m.config.MetadataCache.Ttl <= 0 {
+		return
+	}
+	infoHash = strings.ToLower(infoHash)
+	m.remember(infoHash, metadata, time.Now())
+	if err := m.store.SaveCachedMetadata(ctx, infoHash, metadata); err != nil {
+		m.logger.Err(err).Msgf("error while caching metadata for %s", infoHash)
+	}
+}
+
+func (m *Metadata) Stats() models.CacheStats {
+	m.mu.Lock()
+	entries := m.lru.Len()
+	m.mu.Unlock()
+	stats := models.CacheStats{
+		Entries:      entries,
+		MemoryHits:   m.memoryHits.Load(),
+		DatabaseHits: m.databaseHits.Load(),
+		Misses:       m.misses.Load(),
+	}
+	if total := stats.MemoryHits + stats.DatabaseHits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.MemoryHits+stats.DatabaseHits) / float64(total)
+	}
+	return stats
+}
+
+func (m *Metadata) memory(infoHash string) (*models.TorrentMetadata, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.items[infoHash]
+	if !ok {
+		return nil, false
+	}
+	it := e.Value.(*item)
+	if time.Now().After(it.expiresAt) {
+		m.lru.Remove(e)
+		delete(m.items, infoHash)
+		return nil, false
+	}
+	m.lru.MoveToFront(e)
+	return it.metadata, true
+}
+
+// remember keeps the metadata in memory until its Ttl from fetchedAt elapses.
+func (m *Metadata) remember(infoHash string, metadata *models.TorrentMetadata, fetchedAt time.Time) {
+	size := m.config.MetadataCache.Size
+	if size <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expiresAt := fetchedAt.Add(m.config.MetadataCache.Ttl)
+	if e, ok := m.items[infoHash]; ok {
+		e.Value = &item{infoHash: infoHash, metadata: metadata, expiresAt: expiresAt}
+		m.lru.MoveToFront(e)
+		return
+	}
+	m.items[infoHash] = m.lru.PushFront(&item{infoHash: infoHash, metadata: metadata, expiresAt: expiresAt})
+	for m.lru.Len() > size {
+		oldest := m.lru.Back()
+		m.lru.Remove(oldest)
+		delete(m.items, oldest.Value.(*item).infoHash)
+	}
+}
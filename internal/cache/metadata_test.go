@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/processor-films/internal/config"
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+// fakeStore returns its metadata with fetchedAt regardless of maxAge, as a row read right before it expires.
+type fakeStore struct {
+	metadata  *models.TorrentMetadata
+	fetchedAt time.Time
+	reads     int
+}
+
+func (s *fakeStore) GetCachedMetadata(ctx context.Context, infoHash string, maxAge time.Duration) (*models.TorrentMetadata, time.Time, error) {
+	s.reads++
+	return s.metadata, s.fetchedAt, nil
+}
+
+func (s *fakeStore) SaveCachedMetadata(ctx context.Context, infoHash string, metadata *models.TorrentMetadata) error {
+	return nil
+}
+
+func newTestCache(store Store) *Metadata {
+	logger := zerolog.Nop()
+	cfg := &config.Config{MetadataCache: config.MetadataCache{Size: 10, Ttl: time.Hour}}
+	return New(cfg, &logger, store)
+}
+
+func TestStoredMetadataExpiresFromFetchedAt(t *testing.T) {
+	tests := []struct {
+		name       string
+		fetchedAt  time.Time
+		wantReads  int
+		wantMemory uint64
+	}{
+		{"fresh row is kept in memory", time.Now(), 1, 1},
+		{"row fetched a ttl ago is not kept in memory", time.Now().Add(-time.Hour - time.Minute), 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{metadata: &models.TorrentMetadata{}, fetchedAt: tt.fetchedAt}
+			m := newTestCache(store)
+			for i := 0; i < 2; i++ {
+				if _, ok := m.Get(context.Background(), "ABC"); !ok {
+					t.Fatalf("Get %d missed", i)
+				}
+			}
+			if store.reads != tt.wantReads {
+				t.Errorf("store reads = %d, want %d", store.reads, tt.wantReads)
+			}
+			if stats := m.Stats(); stats.MemoryHits != tt.wantMemory {
+				t.Errorf("memory hits = %d, want %d", stats.MemoryHits, tt.wantMemory)
+			}
+		})
+	}
+}
+
+func TestSetExpiresFromNow(t *testing.T) {
+	store := &fakeStore{}
+	m := newTestCache(store)
+	m.Set(context.Background(), "abc", &models.TorrentMetadata{})
+	if _, ok := m.Get(context.Background(), "ABC"); !ok {
+		t.Fatal("metadata set is not cached")
+	}
+	if store.reads != 0 {
+		t.Errorf("store reads = %d, want 0", store.reads)
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
@@ -19,15 +20,103 @@ type Database struct {
 	Password string `required:"true"`
 }
 
+type Upstream struct {
+	Concurrency      int           `default:"2"`
+	Rps              float64       `default:"2"`
+	Timeout          time.Duration `default:"30s"`
+	Retries          int           `default:"3"`
+	RetryWait        time.Duration `default:"1s" split_words:"true"`
+	RetryMaxWait     time.Duration `default:"10s" split_words:"true"`
+	BreakerThreshold int           `default:"5" split_words:"true"`
+	BreakerCooldown  time.Duration `default:"10m" split_words:"true"`
+}
+
+type DownloadClient struct {
+	Kind     string `default:"qbittorrent"`
+	Username string
+	Password string
+}
+
+// DownloadOptions are applied when adding torrents, SavePath is a template accepting
+// {operation}, {provider}, {title} and {year} placeholders, e.g. /films/{operation}/{title} ({year}).
+type DownloadOptions struct {
+	SavePath   string `split_words:"true"`
+	Category   string
+	Tags       []string
+	Paused     OptionalBool
+	Sequential OptionalBool
+}
+
+// Ranking weights are keyed by scorer name, scorers without weight are disabled, sizes are in bytes.
+type Ranking struct {
+	Weights     map[string]float64 `default:"subtitles:5,seeds:2,resolution:2,codec:1,group:1,size:1"`
+	Resolutions []string           `default:"720p,1080p,2160p,480p"`
+	Codecs      []string           `default:"x264,x265"`
+	Groups      map[string]float64 `default:"yts:1,yify:1,rarbg:0.9,psa:0.8,tigole:0.8"`
+	MinSize     int64              `default:"681574400" split_words:"true"`
+	MaxSize     int64              `default:"4294967296" split_words:"true"`
+}
+
+// MetadataCache Size is the number of entries kept in memory, a Ttl of zero disables the cache.
+type MetadataCache struct {
+	Size int           `default:"1000"`
+	Ttl  time.Duration `default:"720h"`
+}
+
+type Scheduler struct {
+	Enabled   bool          `default:"false"`
+	Festivals string        `default:"0 3 * * *"`
+	Popular   string        `default:"0 4 * * *"`
+	Jitter    time.Duration `default:"0s"`
+}
+
 type Config struct {
-	Host                  string   `default:"0.0.0.0" required:"true" split_words:"true"`
-	Port                  string   `default:"4003" required:"true" split_words:"true"`
-	Debug                 bool     `default:"false"`
-	Database              Database `required:"true" split_words:"true"`
-	TransmissionApiUrl    string   `required:"true" split_words:"true"`
-	TorrentApiUrl         string   `required:"true" split_words:"true"`
-	SubtitlerApiUrl       string   `required:"true" split_words:"true"`
-	TorrentMetadataApiUrl string   `required:"true" split_words:"true"`
+	Host                  string         `default:"0.0.0.0" required:"true" split_words:"true"`
+	Port                  string         `default:"4003" required:"true" split_words:"true"`
+	Debug                 bool           `default:"false"`
+	Database              Database       `required:"true" split_words:"true"`
+	TransmissionApiUrl    string         `required:"true" split_words:"true"`
+	TorrentApiUrl         string         `required:"true" split_words:"true"`
+	SubtitlerApiUrl       string         `required:"true" split_words:"true"`
+	TorrentMetadataApiUrl string         `split_words:"true"`
+	MaxConsecutiveErrors  int            `default:"5" split_words:"true"`
+	BatchSize             int            `default:"10" split_words:"true"`
+	MaxFilmsPerRun        int            `default:"0" split_words:"true"`
+	Workers               int            `default:"1"`
+	TorrentApi            Upstream       `split_words:"true"`
+	SubtitlerApi          Upstream       `split_words:"true"`
+	TorrentMetadataApi    Upstream       `split_words:"true"`
+	TransmissionApi       Upstream       `split_words:"true"`
+	TorrentFile           Upstream       `split_words:"true"`
+	DownloadClient        DownloadClient `split_words:"true"`
+	Download              DownloadOptions
+	FestivalsDownload     DownloadOptions `split_words:"true"`
+	PopularDownload       DownloadOptions `split_words:"true"`
+	Scheduler             Scheduler       `split_words:"true"`
+	DownloadPollInterval  time.Duration   `default:"5m" split_words:"true"`
+	Ranking               Ranking
+	QualityProfiles       QualityProfiles `default:"{}" split_words:"true"`
+	FestivalsProfile      string          `default:"default" split_words:"true"`
+	PopularProfile        string          `default:"default" split_words:"true"`
+	// SubtitleLanguages in priority order, a film is only added when a torrent covers all of them
+	SubtitleLanguages SubtitleLanguages `default:"es-419,es-ES" split_words:"true"`
+	MetadataCache     MetadataCache     `split_words:"true"`
+	// MetadataSource is api (torrent-metadata-api), local (magnet and .torrent files parsed in process)
+	// or auto (local when a .torrent file is available, api otherwise)
+	MetadataSource string `default:"api" split_words:"true"`
+	// TitleSimilarity is the minimum similarity from 0 to 1 between the film and torrent titles
+	TitleSimilarity float64 `default:"0.85" split_words:"true"`
+	YearTolerance   int     `default:"1" split_words:"true"`
+	// GroupAliases maps release group names to their canonical name as alias:group, added to the built-in aliases
+	GroupAliases map[string]string `split_words:"true"`
+	// SubtitleMatchThreshold is the minimum score from 0 to 1 of an online subtitle for a torrent
+	SubtitleMatchThreshold float64 `default:"0.6" split_words:"true"`
+	// SubtitlerDownloadUrl accepts an {id} placeholder, subtitles are not placed when empty
+	SubtitlerDownloadUrl string `split_words:"true"`
+	// SubtitlePlacementAttempts is the number of failed placements after which a subtitle is given up, 0 retries forever
+	SubtitlePlacementAttempts int `default:"5" split_words:"true"`
+	// DownloadPathMap maps download client paths to local paths as from:to, e.g. /downloads:/mnt/films
+	DownloadPathMap string `split_words:"true"`
 }
 
 func New() *Config {
@@ -45,5 +134,25 @@ func Get() (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	if cfg.DownloadClient.Kind != "qbittorrent" && cfg.DownloadClient.Kind != "transmission" {
+		return nil, fmt.Errorf("unsupported download client: %s", cfg.DownloadClient.Kind)
+	}
+	switch cfg.MetadataSource {
+	case "api", "auto":
+		if cfg.TorrentMetadataApiUrl == "" {
+			return nil, fmt.Errorf("torrent metadata api url is required by %s metadata source", cfg.MetadataSource)
+		}
+	case "local":
+	default:
+		return nil, fmt.Errorf("unsupported metadata source: %s", cfg.MetadataSource)
+	}
+	if len(cfg.SubtitleLanguages) == 0 {
+		return nil, fmt.Errorf("at least one subtitle language is required")
+	}
+	for _, name := range []string{cfg.FestivalsProfile, cfg.PopularProfile} {
+		if _, ok := cfg.QualityProfiles[name]; !ok {
+			return nil, fmt.Errorf("unknown quality profile: %s", name)
+		}
+	}
 	return cfg, nil
 }
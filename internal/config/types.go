@@ -0,0 +1,90 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+const DEFAULT_PROFILE = "default"
+
+// OptionalBool is a boolean which knows whether it was set in the environment,
+// used by overrides which must not replace the base value when unset.
+type OptionalBool struct {
+	Set   bool
+	Value bool
+}
+
+func (b *OptionalBool) Decode(value string) error {
+	if value == "" {
+		return nil
+	}
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	b.Set = true
+	b.Value = v
+	return nil
+}
+
+// QualityProfiles are decoded from a json object keyed by profile name and merged over the
+// built-in default profile.
+type QualityProfiles map[string]models.QualityProfile
+
+func (q *QualityProfiles) Decode(value string) error {
+	profiles := QualityProfiles{
+		DEFAULT_PROFILE: {
+			Name:           DEFAULT_PROFILE,
+			Resolutions:    []string{"720p", "1080p"},
+			BannedKeywords: []string{"cam", "camrip", "hdcam", "ts", "hdts", "telesync", "tc", "telecine"},
+		},
+	}
+	if value != "" {
+		var custom map[string]models.QualityProfile
+		if err := json.Unmarshal([]byte(value), &custom); err != nil {
+			return err
+		}
+		for name, profile := range custom {
+			profile.Name = name
+			profiles[name] = profile
+		}
+	}
+	*q = profiles
+	return nil
+}
+
+// SubtitleLanguages are decoded from language tags in priority order, regional tags of the same
+// language are grouped as its variants, e.g. "es-419,es-ES,en" wants Spanish, preferring Latin
+// American over Castilian, and then English.
+type SubtitleLanguages []models.SubtitleLanguage
+
+func (l *SubtitleLanguages) Decode(value string) error {
+	languages := SubtitleLanguages{}
+	index := map[string]int{}
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		code, region, _ := strings.Cut(tag, "-")
+		code = strings.ToLower(code)
+		if len(code) != 2 {
+			return fmt.Errorf("invalid subtitle language: %s", tag)
+		}
+		i, ok := index[code]
+		if !ok {
+			i = len(languages)
+			index[code] = i
+			languages = append(languages, models.SubtitleLanguage{Code: code})
+		}
+		if region != "" {
+			languages[i].Variants = append(languages[i].Variants, code+"-"+strings.ToUpper(region))
+		}
+	}
+	*l = languages
+	return nil
+}
@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"strings"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/rs/zerolog"
 	"github.com/xochilpili/processor-films/internal/config"
 	"github.com/xochilpili/processor-films/internal/models"
@@ -26,7 +26,7 @@ func New(config *config.Config, logger *zerolog.Logger) *Database {
 }
 
 func (d *Database) Connect() error {
-	psqlConn := fmt.Sprintf("host=%s port=5432 user=%s password=%s dbname=%s sslmode=disable", d.config.Database.Host, d.config.Database.Username, d.config.Database.Password, d.config.Database.Name)
+	psqlConn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", d.config.Database.Host, d.config.Database.Port, d.config.Database.Username, d.config.Database.Password, d.config.Database.Name)
 	db, err := sql.Open("postgres", psqlConn)
 	if err != nil {
 		return err
@@ -36,6 +36,11 @@ func (d *Database) Connect() error {
 	if err != nil {
 		return err
 	}
+	err = d.migrate(context.Background())
+	if err != nil {
+		d.logger.Err(err).Msg("error while migrating database schema")
+		return err
+	}
 	return nil
 }
 
@@ -51,34 +56,41 @@ func (p *Database) Close() error {
 	return nil
 }
 
-func (p *Database) GetOlderFilms(ctx context.Context, table string) ([]models.FilmItem, error) {
-	var sqlStmt string = fmt.Sprintf("select (id, provider, title, year, genres, created_at, processed_at) from %s where processed_at between current_timestamp + interval '30 days' and current_timestamp + interval '15 days' and processed = 0 limit 10", table)
-	rows, err := p.db.QueryContext(ctx, sqlStmt)
+// GetOlderFilms returns already attempted films which are still not processed, paginated by id: only films
+// with id greater than afterId are returned.
+func (p *Database) GetOlderFilms(ctx context.Context, table string, afterId int, limit int) ([]models.FilmItem, error) {
+	var sqlStmt string = fmt.Sprintf("select id, provider, title, year, genres from %s where processed_at between current_timestamp + interval '30 days' and current_timestamp + interval '15 days' and processed = 0 and id > $1 order by id limit $2", table)
+	rows, err := p.db.QueryContext(ctx, sqlStmt, afterId, limit)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 	var films []models.FilmItem
 	for rows.Next() {
 		film := models.FilmItem{}
-		if err := rows.Scan(&film.Id, &film.Provider, &film.Title, &film.Year, &film.Genres); err != nil {
+		if err := rows.Scan(&film.Id, &film.Provider, &film.Title, &film.Year, pq.Array(&film.Genres)); err != nil {
 			p.logger.Err(err).Msg("error while fetching film from databse")
 			return nil, err
 		}
 		films = append(films, film)
 	}
-	return films, nil
+	return films, rows.Err()
 }
 
-func (p *Database) GetFilms(ctx context.Context, table string, columns []string, provider string) ([]models.FilmItem, error) {
+// GetFilms returns never attempted films, paginated by id: only films with id greater than afterId are returned.
+func (p *Database) GetFilms(ctx context.Context, table string, columns []string, provider string, afterId int, limit int) ([]models.FilmItem, error) {
 	cols := strings.Join(columns, ",")
-	var sqlStmt string = fmt.Sprintf(`select %s from %s where processed = 0 and processed_at is null limit 10`, cols, table)
+	var sqlStmt string = fmt.Sprintf(`select %s from %s where processed = 0 and processed_at is null and id > $1 order by id limit $2`, cols, table)
+	args := []any{afterId, limit}
 	if provider != "all" && provider != "" {
-		sqlStmt = fmt.Sprintf(`select %s from %s where provider='%s'`, cols, table, provider)
+		sqlStmt = fmt.Sprintf(`select %s from %s where processed = 0 and processed_at is null and id > $1 and provider = $3 order by id limit $2`, cols, table)
+		args = append(args, provider)
 	}
-	rows, err := p.db.QueryContext(ctx, sqlStmt)
+	rows, err := p.db.QueryContext(ctx, sqlStmt, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 	var films []models.FilmItem
 	for rows.Next() {
 		film := models.FilmItem{}
@@ -88,21 +100,36 @@ func (p *Database) GetFilms(ctx context.Context, table string, columns []string,
 		}
 		films = append(films, film)
 	}
-	return films, nil
+	return films, rows.Err()
+}
+
+// FilmExists returns true when the film is in the films table.
+func (p *Database) FilmExists(ctx context.Context, table string, id int) (bool, error) {
+	var sqlStmt string = fmt.Sprintf("select exists (select 1 from %s where id = $1)", table)
+	var exists bool
+	if err := p.db.QueryRowContext(ctx, sqlStmt, id).Scan(&exists); err != nil {
+		p.logger.Err(err).Msgf("error while looking up film id: %d", id)
+		return false, err
+	}
+	return exists, nil
 }
 
-func (p *Database) UpdateProcess(ctx context.Context, table string, id int) {
+func (p *Database) UpdateProcess(ctx context.Context, table string, id int) error {
 	var sqlStmt string = fmt.Sprintf("update %s set processed_at = current_timestamp where id = $1", table)
 	_, err := p.db.ExecContext(ctx, sqlStmt, id)
 	if err != nil {
 		p.logger.Err(err).Msgf("error while updating processed time for id: %d", id)
+		return err
 	}
+	return nil
 }
 
-func (p *Database) ProcessedFilm(ctx context.Context, table string, id int) {
-	var sqlStmt string = fmt.Sprintf("update %s set processed = 1 where id = $1", table)
-	_, err := p.db.ExecContext(ctx, sqlStmt, id)
+func (p *Database) ProcessedFilm(ctx context.Context, table string, id int, hash string) error {
+	var sqlStmt string = fmt.Sprintf("update %s set processed = 1, torrent_hash = $2 where id = $1", table)
+	_, err := p.db.ExecContext(ctx, sqlStmt, id, nullString(hash))
 	if err != nil {
 		p.logger.Err(err).Msgf("error while deleting film id: %d", id)
+		return err
 	}
+	return nil
 }
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+// GetPendingDownloads returns the films added to the download client whose download has not completed yet,
+// along with the last known state of the download if it was tracked before.
+func (p *Database) GetPendingDownloads(ctx context.Context, table string) ([]models.FilmDownload, error) {
+	var sqlStmt string = fmt.Sprintf(`select f.id, f.torrent_hash, d.name, coalesce(d.progress, 0), coalesce(d.ratio, 0), coalesce(d.size, 0),
+		d.content_path, d.added_at from %s f
+		left join film_downloads d on d.operation_type = $1 and d.film_id = f.id
+		where f.processed = 1 and f.torrent_hash is not null and d.completed_at is null
+		order by f.id`, table)
+	rows, err := p.db.QueryContext(ctx, sqlStmt, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var downloads []models.FilmDownload
+	for rows.Next() {
+		download := models.FilmDownload{OperationType: table}
+		var name, contentPath sql.NullString
+		var addedAt sql.NullTime
+		err := rows.Scan(&download.FilmId, &download.Hash, &name, &download.Progress, &download.Ratio, &download.Size, &contentPath, &addedAt)
+		if err != nil {
+			p.logger.Err(err).Msg("error while fetching pending download from database")
+			return nil, err
+		}
+		download.Name = name.String
+		download.ContentPath = contentPath.String
+		download.AddedAt = nullTime(addedAt)
+		downloads = append(downloads, download)
+	}
+	return downloads, rows.Err()
+}
+
+// SaveDownload upserts the download, a name or content path not known anymore (e.g. missing downloads)
+// keeps the stored one.
+func (p *Database) SaveDownload(ctx context.Context, download *models.FilmDownload) error {
+	var sqlStmt string = `insert into film_downloads (operation_type, film_id, hash, name, state, progress, ratio, size, content_path, added_at, completed_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		on conflict (operation_type, film_id) do update set hash = excluded.hash, name = coalesce(excluded.name, film_downloads.name), state = excluded.state,
+		progress = excluded.progress, ratio = excluded.ratio, size = excluded.size, content_path = coalesce(excluded.content_path, film_downloads.content_path),
+		added_at = coalesce(excluded.added_at, film_downloads.added_at), completed_at = excluded.completed_at, updated_at = current_timestamp
+		returning updated_at`
+	err := p.db.QueryRowContext(ctx, sqlStmt, download.OperationType, download.FilmId, download.Hash, nullString(download.Name), download.State,
+		download.Progress, download.Ratio, download.Size, nullString(download.ContentPath), download.AddedAt, download.CompletedAt).Scan(&download.UpdatedAt)
+	if err != nil {
+		p.logger.Err(err).Msgf("error while saving download for film id: %d", download.FilmId)
+		return err
+	}
+	return nil
+}
+
+// GetFilmDownloads returns the downloads of the film, table filters by operation type when not empty.
+func (p *Database) GetFilmDownloads(ctx context.Context, filmId int, table string) ([]models.FilmDownload, error) {
+	var sqlStmt string = fmt.Sprintf("select %s from film_downloads where film_id = $1 and ($2 = '' or operation_type = $2) order by operation_type", downloadColumns)
+	rows, err := p.db.QueryContext(ctx, sqlStmt, filmId, table)
+	if err != nil {
+		return nil, err
+	}
+	downloads, err := scanDownloads(rows)
+	if err != nil {
+		p.logger.Err(err).Msgf("error while fetching downloads for film id: %d", filmId)
+		return nil, err
+	}
+	sqlStmt = fmt.Sprintf("select %s from film_download_subtitles where film_id = $1 and ($2 = '' or operation_type = $2) order by id", subtitleColumns)
+	if err := p.attachSubtitles(ctx, downloads, sqlStmt, filmId, table); err != nil {
+		p.logger.Err(err).Msgf("error while fetching subtitles for film id: %d", filmId)
+		return nil, err
+	}
+	return downloads, nil
+}
+
+// SetDownloadSubtitles stores the online subtitles to be placed once the download completes,
+// replacing the ones stored before for the film.
+func (p *Database) SetDownloadSubtitles(ctx context.Context, download *models.FilmDownload) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var sqlStmt string = `insert into film_downloads (operation_type, film_id, hash, state) values ($1, $2, $3, $4)
+		on conflict (operation_type, film_id) do update set hash = excluded.hash, updated_at = current_timestamp`
+	if _, err := tx.ExecContext(ctx, sqlStmt, download.OperationType, download.FilmId, download.Hash, download.State); err != nil {
+		p.logger.Err(err).Msgf("error while saving download for film id: %d", download.FilmId)
+		return err
+	}
+	sqlStmt = "delete from film_download_subtitles where operation_type = $1 and film_id = $2"
+	if _, err := tx.ExecContext(ctx, sqlStmt, download.OperationType, download.FilmId); err != nil {
+		p.logger.Err(err).Msgf("error while deleting subtitles for film id: %d", download.FilmId)
+		return err
+	}
+	sqlStmt = "insert into film_download_subtitles (operation_type, film_id, language, subtitle_id) values ($1, $2, $3, $4)"
+	for _, subtitle := range download.Subtitles {
+		if _, err := tx.ExecContext(ctx, sqlStmt, download.OperationType, download.FilmId, subtitle.Language, subtitle.SubtitleId); err != nil {
+			p.logger.Err(err).Msgf("error while saving subtitle for film id: %d", download.FilmId)
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetDownloadsAwaitingSubtitles returns completed downloads with the online subtitles not placed yet,
+// subtitles which failed maxAttempts times are left out, a maxAttempts of 0 means no limit.
+func (p *Database) GetDownloadsAwaitingSubtitles(ctx context.Context, maxAttempts int) ([]models.FilmDownload, error) {
+	var sqlStmt string = fmt.Sprintf(`select %s from film_downloads d where completed_at is not null and exists (
+		select 1 from film_download_subtitles s where s.operation_type = d.operation_type and s.film_id = d.film_id
+		and s.path is null and ($1 <= 0 or s.attempts < $1)
+	) order by id`, downloadColumns)
+	rows, err := p.db.QueryContext(ctx, sqlStmt, maxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	downloads, err := scanDownloads(rows)
+	if err != nil {
+		p.logger.Err(err).Msg("error while fetching downloads awaiting subtitles")
+		return nil, err
+	}
+	sqlStmt = fmt.Sprintf("select %s from film_download_subtitles where path is null and ($1 <= 0 or attempts < $1) order by id", subtitleColumns)
+	if err := p.attachSubtitles(ctx, downloads, sqlStmt, maxAttempts); err != nil {
+		p.logger.Err(err).Msg("error while fetching subtitles awaiting placement")
+		return nil, err
+	}
+	return downloads, nil
+}
+
+func (p *Database) UpdateDownloadSubtitle(ctx context.Context, download *models.FilmDownload, subtitle *models.DownloadSubtitle) error {
+	var sqlStmt string = `update film_download_subtitles set path = $1, error = $2, attempts = $3, updated_at = current_timestamp
+		where operation_type = $4 and film_id = $5 and language = $6`
+	_, err := p.db.ExecContext(ctx, sqlStmt, nullString(subtitle.Path), nullString(subtitle.Error), subtitle.Attempts,
+		download.OperationType, download.FilmId, subtitle.Language)
+	if err != nil {
+		p.logger.Err(err).Msgf("error while updating subtitle for film id: %d", download.FilmId)
+		return err
+	}
+	return nil
+}
+
+const downloadColumns = "operation_type, film_id, hash, name, state, progress, ratio, size, content_path, added_at, completed_at, updated_at"
+
+const subtitleColumns = "operation_type, film_id, language, subtitle_id, path, error, attempts"
+
+func scanDownloads(rows *sql.Rows) ([]models.FilmDownload, error) {
+	defer rows.Close()
+	downloads := []models.FilmDownload{}
+	for rows.Next() {
+		var download models.FilmDownload
+		var name, contentPath sql.NullString
+		var addedAt, completedAt sql.NullTime
+		err := rows.Scan(&download.OperationType, &download.FilmId, &download.Hash, &name, &download.State, &download.Progress,
+			&download.Ratio, &download.Size, &contentPath, &addedAt, &completedAt, &download.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		download.Name = name.String
+		download.ContentPath = contentPath.String
+		download.AddedAt = nullTime(addedAt)
+		download.CompletedAt = nullTime(completedAt)
+		downloads = append(downloads, download)
+	}
+	return downloads, rows.Err()
+}
+
+// attachSubtitles adds the subtitles returned by the query to their downloads.
+func (p *Database) attachSubtitles(ctx context.Context, downloads []models.FilmDownload, sqlStmt string, args ...any) error {
+	rows, err := p.db.QueryContext(ctx, sqlStmt, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	index := make(map[string]int, len(downloads))
+	for i, download := range downloads {
+		index[fmt.Sprintf("%s/%d", download.OperationType, download.FilmId)] = i
+	}
+	for rows.Next() {
+		var operationType string
+		var filmId int
+		var subtitle models.DownloadSubtitle
+		var path, subtitleError sql.NullString
+		if err := rows.Scan(&operationType, &filmId, &subtitle.Language, &subtitle.SubtitleId, &path, &subtitleError, &subtitle.Attempts); err != nil {
+			return err
+		}
+		subtitle.Path = path.String
+		subtitle.Error = subtitleError.String
+		if i, ok := index[fmt.Sprintf("%s/%d", operationType, filmId)]; ok {
+			downloads[i].Subtitles = append(downloads[i].Subtitles, subtitle)
+		}
+	}
+	return rows.Err()
+}
+
+func nullTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	t := value.Time
+	return &t
+}
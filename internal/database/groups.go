@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+func (p *Database) GetGroupAliases(ctx context.Context) ([]models.GroupAlias, error) {
+	var sqlStmt string = "select alias, group_name from group_aliases order by alias"
+	rows, err := p.db.QueryContext(ctx, sqlStmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	aliases := []models.GroupAlias{}
+	for rows.Next() {
+		var alias models.GroupAlias
+		if err := rows.Scan(&alias.Alias, &alias.Group); err != nil {
+			p.logger.Err(err).Msg("error while fetching group aliases from database")
+			return nil, err
+		}
+		aliases = append(aliases, alias)
+	}
+	return aliases, rows.Err()
+}
+
+func (p *Database) SaveGroupAlias(ctx context.Context, alias models.GroupAlias) error {
+	var sqlStmt string = `insert into group_aliases (alias, group_name) values ($1, $2)
+		on conflict (alias) do update set group_name = excluded.group_name, created_at = current_timestamp`
+	_, err := p.db.ExecContext(ctx, sqlStmt, alias.Alias, alias.Group)
+	if err != nil {
+		p.logger.Err(err).Msgf("error while saving group alias: %s", alias.Alias)
+		return err
+	}
+	return nil
+}
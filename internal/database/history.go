@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+func (p *Database) AddFilmAttempt(ctx context.Context, attempt *models.FilmProcessAttempt) error {
+	var sqlStmt string = `insert into film_process_attempts (job_id, operation_type, film_id, search_term, candidates, torrent, score, subtitle_source, outcome, error, duration_ms)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) returning id, created_at`
+	var score sql.NullFloat64
+	if attempt.Score != nil {
+		score = sql.NullFloat64{Float64: *attempt.Score, Valid: true}
+	}
+	err := p.db.QueryRowContext(ctx, sqlStmt, attempt.JobId, attempt.OperationType, attempt.FilmId, attempt.SearchTerm, attempt.Candidates,
+		nullString(attempt.Torrent), score, attempt.SubtitleSource, attempt.Outcome, nullString(attempt.Error), attempt.DurationMs).Scan(&attempt.Id, &attempt.CreatedAt)
+	if err != nil {
+		p.logger.Err(err).Msgf("error while adding process attempt for film id: %d", attempt.FilmId)
+		return err
+	}
+	return nil
+}
+
+// GetFilmAttempts returns the process attempts of the film newest first, table filters by operation type when not empty.
+func (p *Database) GetFilmAttempts(ctx context.Context, filmId int, table string) ([]models.FilmProcessAttempt, error) {
+	var sqlStmt string = `select id, job_id, operation_type, film_id, search_term, candidates, torrent, score, subtitle_source, outcome, error, duration_ms, created_at
+		from film_process_attempts where film_id = $1 and ($2 = '' or operation_type = $2) order by created_at desc, id desc`
+	rows, err := p.db.QueryContext(ctx, sqlStmt, filmId, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	attempts := []models.FilmProcessAttempt{}
+	for rows.Next() {
+		var attempt models.FilmProcessAttempt
+		var torrent, errText sql.NullString
+		var score sql.NullFloat64
+		err := rows.Scan(&attempt.Id, &attempt.JobId, &attempt.OperationType, &attempt.FilmId, &attempt.SearchTerm, &attempt.Candidates,
+			&torrent, &score, &attempt.SubtitleSource, &attempt.Outcome, &errText, &attempt.DurationMs, &attempt.CreatedAt)
+		if err != nil {
+			p.logger.Err(err).Msgf("error while fetching process attempts for film id: %d", filmId)
+			return nil, err
+		}
+		attempt.Torrent = torrent.String
+		attempt.Error = errText.String
+		if score.Valid {
+			attempt.Score = &score.Float64
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
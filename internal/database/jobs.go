@@ -0,0 +1,157 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+func (p *Database) CreateJob(ctx context.Context, job *models.Job) error {
+	profile, err := nullJson(job.Profile, job.Profile != nil)
+	if err != nil {
+		return err
+	}
+	var sqlStmt string = "insert into jobs (operation_type, provider, batch_size, max_films, profile, status) values ($1, $2, $3, $4, $5, $6) returning id, started_at"
+	err = p.db.QueryRowContext(ctx, sqlStmt, job.OperationType, job.Provider, job.BatchSize, job.MaxFilms, profile, job.Status).Scan(&job.Id, &job.StartedAt)
+	if err != nil {
+		p.logger.Err(err).Msgf("error while creating %s job", job.OperationType)
+		return err
+	}
+	return nil
+}
+
+func (p *Database) UpdateJob(ctx context.Context, job *models.Job) error {
+	var sqlStmt string = "update jobs set status = $1, finished_at = $2, error = $3 where id = $4"
+	_, err := p.db.ExecContext(ctx, sqlStmt, job.Status, job.FinishedAt, nullString(job.Error), job.Id)
+	if err != nil {
+		p.logger.Err(err).Msgf("error while updating job id: %d", job.Id)
+		return err
+	}
+	return nil
+}
+
+// InterruptJobs fails the running and queued jobs of the operation type left behind by a stopped
+// process, it returns the number of jobs updated.
+func (p *Database) InterruptJobs(ctx context.Context, operationType string, reason string) (int64, error) {
+	var sqlStmt string = "update jobs set status = $1, finished_at = current_timestamp, error = $2 where operation_type = $3 and status in ($4, $5)"
+	res, err := p.db.ExecContext(ctx, sqlStmt, models.JOB_FAILED, reason, operationType, models.JOB_RUNNING, models.JOB_QUEUED)
+	if err != nil {
+		p.logger.Err(err).Msgf("error while interrupting %s jobs", operationType)
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (p *Database) AddJobFilm(ctx context.Context, jobId int, film *models.JobFilm) error {
+	breakdown, err := nullJson(film.ScoreBreakdown, film.ScoreBreakdown != nil)
+	if err != nil {
+		return err
+	}
+	var sqlStmt string = "insert into job_films (job_id, film_id, title, outcome, torrent, error, error_kind, score, score_breakdown) values ($1, $2, $3, $4, $5, $6, $7, $8, $9) returning created_at"
+	err = p.db.QueryRowContext(ctx, sqlStmt, jobId, film.FilmId, film.Title, film.Outcome, nullString(film.Torrent), nullString(film.Error), nullString(film.ErrorKind),
+		sql.NullFloat64{Float64: film.Score, Valid: film.ScoreBreakdown != nil}, breakdown).Scan(&film.CreatedAt)
+	if err != nil {
+		p.logger.Err(err).Msgf("error while adding film id: %d to job id: %d", film.FilmId, jobId)
+		return err
+	}
+	return nil
+}
+
+func (p *Database) GetJobs(ctx context.Context, limit int) ([]models.Job, error) {
+	var sqlStmt string = "select id, operation_type, provider, batch_size, max_films, profile, status, started_at, finished_at, error from jobs order by id desc limit $1"
+	rows, err := p.db.QueryContext(ctx, sqlStmt, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	jobs := []models.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			p.logger.Err(err).Msg("error while fetching job from database")
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// GetJob returns the job with its films outcomes, nil is returned when job does not exists.
+func (p *Database) GetJob(ctx context.Context, id int) (*models.Job, error) {
+	var sqlStmt string = "select id, operation_type, provider, batch_size, max_films, profile, status, started_at, finished_at, error from jobs where id = $1"
+	job, err := scanJob(p.db.QueryRowContext(ctx, sqlStmt, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		p.logger.Err(err).Msgf("error while fetching job id: %d", id)
+		return nil, err
+	}
+
+	sqlStmt = "select film_id, title, outcome, torrent, error, error_kind, score, score_breakdown, created_at from job_films where job_id = $1 order by id"
+	rows, err := p.db.QueryContext(ctx, sqlStmt, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	job.Films = []models.JobFilm{}
+	for rows.Next() {
+		var film models.JobFilm
+		var torrent, errMsg, errKind, breakdown sql.NullString
+		var score sql.NullFloat64
+		if err := rows.Scan(&film.FilmId, &film.Title, &film.Outcome, &torrent, &errMsg, &errKind, &score, &breakdown, &film.CreatedAt); err != nil {
+			p.logger.Err(err).Msgf("error while fetching films for job id: %d", id)
+			return nil, err
+		}
+		film.Torrent = torrent.String
+		film.Error = errMsg.String
+		film.ErrorKind = errKind.String
+		film.Score = score.Float64
+		if breakdown.Valid {
+			if err := json.Unmarshal([]byte(breakdown.String), &film.ScoreBreakdown); err != nil {
+				return nil, err
+			}
+		}
+		job.Films = append(job.Films, film)
+	}
+	return job, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row scanner) (*models.Job, error) {
+	var job models.Job
+	var finishedAt sql.NullTime
+	var errMsg, profile sql.NullString
+	if err := row.Scan(&job.Id, &job.OperationType, &job.Provider, &job.BatchSize, &job.MaxFilms, &profile, &job.Status, &job.StartedAt, &finishedAt, &errMsg); err != nil {
+		return nil, err
+	}
+	if profile.Valid {
+		if err := json.Unmarshal([]byte(profile.String), &job.Profile); err != nil {
+			return nil, err
+		}
+	}
+	job.FinishedAt = nullTime(finishedAt)
+	job.Error = errMsg.String
+	return &job, nil
+}
+
+// nullJson encodes the value as json, it is stored as null when not valid.
+func nullJson(value any, valid bool) (sql.NullString, error) {
+	if !valid {
+		return sql.NullString{}, nil
+	}
+	out, err := json.Marshal(value)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(out), Valid: true}, nil
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
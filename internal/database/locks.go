@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+)
+
+var ErrLockNotAcquired = errors.New("advisory lock is held by another session")
+
+// AcquireLock takes a session level postgres advisory lock for the given name, when wait is false
+// it returns ErrLockNotAcquired instead of blocking. The returned func releases the lock.
+func (p *Database) AcquireLock(ctx context.Context, name string, wait bool) (func(), error) {
+	key := lockKey(name)
+	// advisory locks belong to a session, so the same connection must be used to release it
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if wait {
+		_, err = conn.ExecContext(ctx, "select pg_advisory_lock($1)", key)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	} else {
+		var acquired bool
+		err = conn.QueryRowContext(ctx, "select pg_try_advisory_lock($1)", key).Scan(&acquired)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if !acquired {
+			conn.Close()
+			return nil, ErrLockNotAcquired
+		}
+	}
+
+	release := func() {
+		if _, err := conn.ExecContext(context.Background(), "select pg_advisory_unlock($1)", key); err != nil {
+			p.logger.Err(err).Msgf("error while releasing advisory lock: %s", name)
+		}
+		conn.Close()
+	}
+	return release, nil
+}
+
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("processor-films:" + name))
+	return int64(h.Sum64())
+}
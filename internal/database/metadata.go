@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+// GetCachedMetadata returns the metadata cached for the infohash and when it was fetched, nil when it
+// is missing or older than maxAge.
+func (p *Database) GetCachedMetadata(ctx context.Context, infoHash string, maxAge time.Duration) (*models.TorrentMetadata, time.Time, error) {
+	var sqlStmt string = "select metadata, fetched_at from torrent_metadata where info_hash = $1 and fetched_at > $2"
+	var raw []byte
+	var fetchedAt time.Time
+	err := p.db.QueryRowContext(ctx, sqlStmt, infoHash, time.Now().UTC().Add(-maxAge)).Scan(&raw, &fetchedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var metadata models.TorrentMetadata
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		return nil, time.Time{}, err
+	}
+	return &metadata, fetchedAt, nil
+}
+
+func (p *Database) SaveCachedMetadata(ctx context.Context, infoHash string, metadata *models.TorrentMetadata) error {
+	raw, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	var sqlStmt string = `insert into torrent_metadata (info_hash, metadata, fetched_at) values ($1, $2, $3)
+		on conflict (info_hash) do update set metadata = excluded.metadata, fetched_at = excluded.fetched_at`
+	_, err = p.db.ExecContext(ctx, sqlStmt, infoHash, raw, time.Now().UTC())
+	if err != nil {
+		p.logger.Err(err).Msgf("error while saving metadata for infohash: %s", infoHash)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package database
+
+import "context"
+
+// schema holds the tables owned by this service and the columns it adds to the films tables,
+// which are managed by the ingestion services.
+var schema = []string{
+	`create table if not exists jobs (
+		id serial primary key,
+		operation_type varchar(64) not null,
+		provider varchar(64) not null,
+		status varchar(32) not null,
+		started_at timestamp not null default current_timestamp,
+		finished_at timestamp,
+		error text
+	)`,
+	`create table if not exists job_films (
+		id serial primary key,
+		job_id integer not null references jobs(id) on delete cascade,
+		film_id integer not null,
+		title text not null,
+		outcome varchar(32) not null,
+		torrent text,
+		error text,
+		created_at timestamp not null default current_timestamp
+	)`,
+	`alter table job_films add column if not exists error_kind varchar(32)`,
+	`alter table jobs add column if not exists batch_size integer not null default 0`,
+	`alter table jobs add column if not exists max_films integer not null default 0`,
+	`alter table if exists films_festivals add column if not exists torrent_hash varchar(64)`,
+	`alter table if exists films_popular add column if not exists torrent_hash varchar(64)`,
+	`create table if not exists film_downloads (
+		id serial primary key,
+		operation_type varchar(64) not null,
+		film_id integer not null,
+		hash varchar(64) not null,
+		name text,
+		state varchar(32) not null,
+		progress double precision not null default 0,
+		ratio double precision not null default 0,
+		size bigint not null default 0,
+		content_path text,
+		added_at timestamp,
+		completed_at timestamp,
+		updated_at timestamp not null default current_timestamp,
+		unique (operation_type, film_id)
+	)`,
+	`alter table job_films add column if not exists score double precision`,
+	`alter table job_films add column if not exists score_breakdown jsonb`,
+	`alter table jobs add column if not exists profile jsonb`,
+	`create table if not exists torrent_metadata (
+		info_hash varchar(64) primary key,
+		metadata jsonb not null,
+		fetched_at timestamp not null
+	)`,
+	`create table if not exists group_aliases (
+		alias varchar(128) primary key,
+		group_name varchar(128) not null,
+		created_at timestamp not null default current_timestamp
+	)`,
+	`create table if not exists film_process_attempts (
+		id serial primary key,
+		job_id integer not null references jobs(id) on delete cascade,
+		operation_type varchar(64) not null,
+		film_id integer not null,
+		search_term text not null,
+		candidates integer not null default 0,
+		torrent text,
+		score double precision,
+		subtitle_source varchar(32) not null,
+		outcome varchar(32) not null,
+		error text,
+		duration_ms bigint not null default 0,
+		created_at timestamp not null default current_timestamp
+	)`,
+	`create index if not exists film_process_attempts_film_idx on film_process_attempts (film_id, operation_type)`,
+	`create table if not exists film_download_subtitles (
+		id serial primary key,
+		operation_type varchar(64) not null,
+		film_id integer not null,
+		language varchar(16) not null,
+		subtitle_id integer not null,
+		path text,
+		error text,
+		attempts integer not null default 0,
+		updated_at timestamp not null default current_timestamp,
+		unique (operation_type, film_id, language)
+	)`,
+}
+
+func (p *Database) migrate(ctx context.Context) error {
+	for _, stmt := range schema {
+		if _, err := p.db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package groups
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+// defaultAliases are the aliases known without configuration, keyed by normalized alias.
+var defaultAliases = map[string]string{
+	"yify":    "yts",
+	"rartv":   "rarbg",
+	"rarbgx":  "rarbg",
+	"psarips": "psa",
+}
+
+var domainRe = regexp.MustCompile(`[._ -](mx|am|lt|ag|to|com|org|net|me|is|ws|lol|bz|tv)$`)
+
+// Aliases maps the names release groups are written with to a canonical name.
+type Aliases struct {
+	mu      sync.RWMutex
+	aliases map[string]string
+}
+
+// New returns the built-in aliases merged with the configured ones, configured aliases win.
+func New(configured map[string]string) *Aliases {
+	a := &Aliases{aliases: map[string]string{}}
+	for alias, group := range defaultAliases {
+		a.aliases[alias] = group
+	}
+	for alias, group := range configured {
+		a.Set(alias, group)
+	}
+	return a
+}
+
+// Normalize lower cases the group and removes brackets, domains and surrounding punctuation,
+// e.g. "[YTS.MX]", "yts-mx" and "YTS" are all "yts".
+func Normalize(group string) string {
+	group = strings.ToLower(strings.TrimSpace(group))
+	group = strings.Trim(group, "[](){}<>-_. ")
+	group = domainRe.ReplaceAllString(group, "")
+	return strings.Trim(group, "-_. ")
+}
+
+// Canonical returns the canonical name of the group, unknown groups are returned normalized.
+func (a *Aliases) Canonical(group string) string {
+	group = Normalize(group)
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if canonical, ok := a.aliases[group]; ok {
+		return canonical
+	}
+	return group
+}
+
+// Set adds or replaces an alias, the group is stored by its canonical name so aliases do not chain.
+func (a *Aliases) Set(alias string, group string) {
+	alias, group = Normalize(alias), Normalize(group)
+	if alias == "" || group == "" {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if canonical, ok := a.aliases[group]; ok {
+		group = canonical
+	}
+	if alias == group {
+		delete(a.aliases, alias)
+		return
+	}
+	a.aliases[alias] = group
+	// aliases of the alias now point to its group
+	for k, v := range a.aliases {
+		if v == alias {
+			a.aliases[k] = group
+		}
+	}
+}
+
+// List returns the aliases sorted by group and alias.
+func (a *Aliases) List() []models.GroupAlias {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	list := make([]models.GroupAlias, 0, len(a.aliases))
+	for alias, group := range a.aliases {
+		list = append(list, models.GroupAlias{Alias: alias, Group: group})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Group != list[j].Group {
+			return list[i].Group < list[j].Group
+		}
+		return list[i].Alias < list[j].Alias
+	})
+	return list
+}
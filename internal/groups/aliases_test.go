@@ -0,0 +1,98 @@
+package groups
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		group string
+		want  string
+	}{
+		{"YTS", "yts"},
+		{"[YTS.MX]", "yts"},
+		{"(YTS.AM)", "yts"},
+		{"yts-mx", "yts"},
+		{"YTS_LT", "yts"},
+		{" -RARBG- ", "rarbg"},
+		{"{EtHD}", "ethd"},
+		{"1337x.to", "1337x"},
+		{"SPARKS", "sparks"},
+		{"ettv", "ettv"},
+		{"", ""},
+		{"[]", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.group, func(t *testing.T) {
+			if got := Normalize(tt.group); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.group, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	a := New(map[string]string{"[EVO.MX]": "Evolution"})
+	tests := []struct {
+		group string
+		want  string
+	}{
+		{"YIFY", "yts"},
+		{"[YTS.MX]", "yts"},
+		{"yts-mx", "yts"},
+		{"RARTV", "rarbg"},
+		{"evo", "evolution"},
+		{"Evo.mx", "evolution"},
+		{"SPARKS", "sparks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.group, func(t *testing.T) {
+			if got := a.Canonical(tt.group); got != tt.want {
+				t.Errorf("Canonical(%q) = %q, want %q", tt.group, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	a := New(nil)
+	a.Set("x264-rg", "RARTV")
+	if got := a.Canonical("x264-rg"); got != "rarbg" {
+		t.Errorf("alias of an alias: got %q, want rarbg", got)
+	}
+	a.Set("rarbg", "RBG")
+	for _, group := range []string{"rartv", "rarbgx", "x264-rg", "rarbg"} {
+		if got := a.Canonical(group); got != "rbg" {
+			t.Errorf("Canonical(%q) after regrouping = %q, want rbg", group, got)
+		}
+	}
+	a.Set("yify", "yts")
+	a.Set("yts", "yify")
+	if got := a.Canonical("yify"); got != "yts" {
+		t.Errorf("alias set to itself: got %q, want yts", got)
+	}
+	a.Set("", "yts")
+	a.Set("psa", "")
+	for _, alias := range a.List() {
+		if alias.Alias == "" || alias.Group == "" {
+			t.Errorf("empty alias stored: %+v", alias)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	a := New(map[string]string{"ION10": "PSA"})
+	want := []models.GroupAlias{
+		{Alias: "ion10", Group: "psa"},
+		{Alias: "psarips", Group: "psa"},
+		{Alias: "rarbgx", Group: "rarbg"},
+		{Alias: "rartv", Group: "rarbg"},
+		{Alias: "yify", Group: "yts"},
+	}
+	if got := a.List(); !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %+v, want %+v", got, want)
+	}
+}
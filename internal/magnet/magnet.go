@@ -0,0 +1,88 @@
+package magnet
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+var ErrInvalidMagnet = errors.New("invalid magnet uri")
+
+const btihPrefix = "urn:btih:"
+
+// InfoHash returns the lower case hex v1 infohash of a magnet uri, base32 encoded hashes are converted.
+func InfoHash(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "magnet" {
+		return "", ErrInvalidMagnet
+	}
+	for _, xt := range u.Query()["xt"] {
+		if !strings.HasPrefix(strings.ToLower(xt), btihPrefix) {
+			continue
+		}
+		return normalizeHash(xt[len(btihPrefix):])
+	}
+	return "", ErrInvalidMagnet
+}
+
+func normalizeHash(hash string) (string, error) {
+	switch len(hash) {
+	case 40:
+		if _, err := hex.DecodeString(hash); err != nil {
+			return "", ErrInvalidMagnet
+		}
+		return strings.ToLower(hash), nil
+	case 32:
+		raw, err := base32.StdEncoding.DecodeString(strings.ToUpper(hash))
+		if err != nil {
+			return "", ErrInvalidMagnet
+		}
+		return hex.EncodeToString(raw), nil
+	}
+	return "", ErrInvalidMagnet
+}
+
+// Magnet holds the fields of a magnet uri, Sources are the urls of the .torrent file (xs and as).
+type Magnet struct {
+	InfoHash string
+	Name     string
+	Trackers []string
+	Sources  []string
+}
+
+// Parse parses a magnet uri, it fails when the uri has no valid v1 infohash.
+func Parse(uri string) (*Magnet, error) {
+	hash, err := InfoHash(uri)
+	if err != nil {
+		return nil, err
+	}
+	u, _ := url.Parse(uri)
+	query := u.Query()
+	m := &Magnet{InfoHash: hash, Name: query.Get("dn"), Trackers: query["tr"]}
+	for _, key := range []string{"xs", "as"} {
+		for _, source := range query[key] {
+			if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+				m.Sources = append(m.Sources, source)
+			}
+		}
+	}
+	return m, nil
+}
+
+// Build returns the magnet uri of the infohash with its name and trackers.
+func Build(infoHash string, name string, trackers []string) string {
+	query := url.Values{}
+	if name != "" {
+		query.Set("dn", name)
+	}
+	for _, tracker := range trackers {
+		query.Add("tr", tracker)
+	}
+	uri := "magnet:?xt=" + btihPrefix + infoHash
+	if len(query) > 0 {
+		uri += "&" + query.Encode()
+	}
+	return uri
+}
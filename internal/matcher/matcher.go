@@ -0,0 +1,221 @@
+package matcher
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xochilpili/processor-films/internal/groups"
+	"github.com/xochilpili/processor-films/internal/models"
+	"github.com/xochilpili/processor-films/internal/release"
+)
+
+// weights of each aspect of a (torrent, subtitle) pair, duration only counts when both the
+// torrent runtime and the subtitle duration are known.
+var weights = map[string]float64{
+	"group":      3,
+	"source":     2,
+	"resolution": 2,
+	"release":    2,
+	"duration":   1,
+}
+
+// sourceClasses groups the sources encoded from the same master, their subtitles are usually in sync.
+var sourceClasses = map[string]string{
+	"webdl":  "web",
+	"webrip": "web",
+	"web":    "web",
+	"bluray": "bluray",
+	"bdrip":  "bluray",
+	"brrip":  "bluray",
+	"dvdrip": "dvd",
+	"hdtv":   "hdtv",
+}
+
+// Matcher scores subtitles against torrents, release groups are compared by their canonical
+// name as subtitles are uploaded with whatever name the uploader used.
+type Matcher struct {
+	groups *groups.Aliases
+}
+
+func New(aliases *groups.Aliases) *Matcher {
+	return &Matcher{groups: aliases}
+}
+
+// Pair is a torrent with a subtitle and how well they match from 0 to 1.
+type Pair struct {
+	Torrent   *models.Torrent
+	Subtitle  *models.Subtitle
+	Score     float64
+	Breakdown map[string]float64
+}
+
+// Best scores every (torrent, subtitle) pair and returns the best one, later pairs only win
+// with a higher score.
+func (m *Matcher) Best(torrents []models.Torrent, subs []models.Subtitle) (Pair, bool) {
+	var best Pair
+	found := false
+	for i := range torrents {
+		pair, ok := m.Match(&torrents[i], subs)
+		if ok && (!found || pair.Score > best.Score) {
+			best = pair
+			found = true
+		}
+	}
+	return best, found
+}
+
+// Match returns the subtitle which best matches the torrent.
+func (m *Matcher) Match(torrent *models.Torrent, subs []models.Subtitle) (Pair, bool) {
+	var best Pair
+	found := false
+	for i := range subs {
+		score, breakdown := m.Score(*torrent, subs[i])
+		if !found || score > best.Score {
+			best = Pair{Torrent: torrent, Subtitle: &subs[i], Score: score, Breakdown: breakdown}
+			found = true
+		}
+	}
+	return best, found
+}
+
+// Score returns how well the subtitle matches the torrent from 0 to 1 with the score of each aspect,
+// unknown aspects score 0.5.
+func (m *Matcher) Score(torrent models.Torrent, subtitle models.Subtitle) (float64, map[string]float64) {
+	breakdown := map[string]float64{
+		"group":      valuesScore(m.groups.Canonical(torrent.Group), subtitle.Group, m.groups.Canonical, nil),
+		"source":     valuesScore(normalizeSource(torrent.Quality), subtitle.Quality, normalizeSource, sourceClass),
+		"resolution": valuesScore(strings.ToLower(torrent.Resolution), subtitle.Resolution, strings.ToLower, nil),
+		"release":    releaseScore(torrent.Title, subtitle),
+	}
+	if score, ok := durationScore(torrent.Runtime, subtitle.Duration); ok {
+		breakdown["duration"] = score
+	}
+	var total, sum float64
+	for name, score := range breakdown {
+		total += weights[name] * score
+		sum += weights[name]
+	}
+	return total / sum, breakdown
+}
+
+// normalizeSource drops spaces and hyphens, e.g. WEB-DL, WEB DL and WEBDL are the same source.
+func normalizeSource(source string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.ToLower(source))
+}
+
+func sourceClass(source string) string {
+	return sourceClasses[source]
+}
+
+// valuesScore scores 1 when a subtitle value equals the torrent value, 0.8 when they belong to
+// the same class, 0 when they differ and 0.5 when either is unknown.
+func valuesScore(value string, values []string, normalize func(string) string, class func(string) string) float64 {
+	if value == "" {
+		return 0.5
+	}
+	known := false
+	score := 0.0
+	for _, v := range values {
+		v = normalize(v)
+		if v == "" {
+			continue
+		}
+		known = true
+		if v == value {
+			return 1
+		}
+		if class != nil && class(v) != "" && class(v) == class(value) {
+			score = 0.8
+		}
+	}
+	if !known {
+		return 0.5
+	}
+	return score
+}
+
+var releaseNameRe = regexp.MustCompile(`[^\s,;/]+[.\-][^\s,;/]+`)
+
+// releaseScore compares the torrent name with the release names in the subtitle title and
+// description by the share of common tokens.
+func releaseScore(name string, subtitle models.Subtitle) float64 {
+	tokens := tokenSet(name)
+	if len(tokens) == 0 {
+		return 0.5
+	}
+	candidates := append([]string{subtitle.Title}, releaseNameRe.FindAllString(subtitle.Description, -1)...)
+	best := 0.0
+	for _, candidate := range candidates {
+		other := tokenSet(candidate)
+		if len(other) == 0 {
+			continue
+		}
+		common := 0
+		for token := range other {
+			if tokens[token] {
+				common++
+			}
+		}
+		best = math.Max(best, float64(common)/float64(len(tokens)+len(other)-common))
+	}
+	return best
+}
+
+func tokenSet(name string) map[string]bool {
+	set := map[string]bool{}
+	for _, token := range release.NormalizeTitle(name) {
+		set[token] = true
+	}
+	return set
+}
+
+var clockRe = regexp.MustCompile(`^(\d{1,3}):(\d{2})(?::(\d{2}))?`)
+var minutesRe = regexp.MustCompile(`(?i)^(\d{2,3})\s*(?:m|min|mins|minutes)?$`)
+
+// durationScore compares the torrent runtime in minutes with the subtitle durations, it is only
+// known when both are.
+func durationScore(runtime int, durations []string) (float64, bool) {
+	if runtime <= 0 {
+		return 0, false
+	}
+	best, known := 0.0, false
+	for _, d := range durations {
+		minutes, ok := parseDuration(strings.TrimSpace(d))
+		if !ok {
+			continue
+		}
+		known = true
+		diff := math.Abs(minutes - float64(runtime))
+		switch {
+		case diff <= 2:
+			best = 1
+		case diff <= 5:
+			best = math.Max(best, 0.5)
+		}
+	}
+	return best, known
+}
+
+// parseDuration parses durations as h:mm:ss, mm:ss or minutes into minutes.
+func parseDuration(value string) (float64, bool) {
+	if m := clockRe.FindStringSubmatch(value); m != nil {
+		a, _ := strconv.Atoi(m[1])
+		b, _ := strconv.Atoi(m[2])
+		if m[3] == "" {
+			if a < 5 {
+				// h:mm, films are not shorter than 5 minutes
+				return float64(a*60 + b), true
+			}
+			return float64(a) + float64(b)/60, true
+		}
+		c, _ := strconv.Atoi(m[3])
+		return float64(a*60+b) + float64(c)/60, true
+	}
+	if m := minutesRe.FindStringSubmatch(value); m != nil {
+		minutes, _ := strconv.Atoi(m[1])
+		return float64(minutes), true
+	}
+	return 0, false
+}
@@ -0,0 +1,103 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/xochilpili/processor-films/internal/groups"
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+func TestBest(t *testing.T) {
+	m := New(groups.New(nil))
+	torrents := []models.Torrent{
+		{Title: "Alien.1979.720p.WEBRip.x264-GalaxyRG", Group: "GalaxyRG", Quality: "WEBRip", Resolution: "720p"},
+		// groups are compared by canonical name, YIFY is YTS
+		{Title: "Alien.1979.1080p.BluRay.x264-YTS", Group: "YIFY", Quality: "BluRay", Resolution: "1080p"},
+		{Title: "Alien.1979.1080p.BluRay.x264-SPARKS", Group: "SPARKS", Quality: "BluRay", Resolution: "1080p"},
+	}
+	subs := []models.Subtitle{
+		{Id: 1, Title: "Alien 1979 720p HDTV", Quality: []string{"HDTV"}, Resolution: []string{"720p"}},
+		{Id: 2, Title: "Alien.1979.1080p.BluRay.x264-YTS", Group: []string{"YTS"}, Quality: []string{"BluRay"}, Resolution: []string{"1080p"}},
+	}
+
+	pair, ok := m.Best(torrents, subs)
+	if !ok {
+		t.Fatal("Best() found no pair")
+	}
+	// the perfect match on a later torrent beats the partial matches before it
+	if pair.Torrent != &torrents[1] || pair.Subtitle.Id != 2 {
+		t.Errorf("Best() = %s with subtitle %d, score %.2f", pair.Torrent.Title, pair.Subtitle.Id, pair.Score)
+	}
+	if pair.Score != 1 {
+		t.Errorf("Best() score = %f, breakdown = %v, want 1", pair.Score, pair.Breakdown)
+	}
+
+	// equal scores keep the earlier torrent
+	same := []models.Torrent{torrents[2], torrents[2]}
+	if pair, _ := m.Best(same, subs); pair.Torrent != &same[0] {
+		t.Errorf("Best() of equal torrents = %p, want the first one", pair.Torrent)
+	}
+
+	if _, ok := m.Best(torrents, nil); ok {
+		t.Error("Best() without subtitles found a pair")
+	}
+}
+
+func TestSourceScore(t *testing.T) {
+	m := New(groups.New(nil))
+	tests := []struct {
+		torrent string
+		sub     []string
+		want    float64
+	}{
+		{"WEB-DL", []string{"WEB-DL"}, 1},
+		{"WEB-DL", []string{"WEB DL", "WEBDL"}, 1},
+		{"WEB-DL", []string{"WEBRip"}, 0.8},
+		{"WEBRip", []string{"web dl"}, 0.8},
+		{"WEBRip", []string{"BluRay", "WEB"}, 0.8},
+		{"BRRip", []string{"BluRay"}, 0.8},
+		{"BluRay", []string{"WEBRip"}, 0},
+		{"", []string{"WEBRip"}, 0.5},
+		{"WEBRip", nil, 0.5},
+	}
+	for _, tt := range tests {
+		_, breakdown := m.Score(models.Torrent{Quality: tt.torrent}, models.Subtitle{Quality: tt.sub})
+		if got := breakdown["source"]; got != tt.want {
+			t.Errorf("source score of %q and %v = %f, want %f", tt.torrent, tt.sub, got, tt.want)
+		}
+	}
+}
+
+func TestDurationScore(t *testing.T) {
+	m := New(groups.New(nil))
+	tests := []struct {
+		runtime   int
+		durations []string
+		want      float64
+		known     bool
+	}{
+		{117, []string{"01:57:10"}, 1, true},
+		{117, []string{"1:56"}, 1, true},
+		{117, []string{"118 min"}, 1, true},
+		{117, []string{"114"}, 0.5, true},
+		{117, []string{"95 min"}, 0, true},
+		{117, []string{"95 min", "117:30"}, 1, true},
+		{117, []string{"unknown"}, 0, false},
+		{117, nil, 0, false},
+		{0, []string{"01:57:10"}, 0, false},
+	}
+	for _, tt := range tests {
+		_, breakdown := m.Score(models.Torrent{Runtime: tt.runtime}, models.Subtitle{Duration: tt.durations})
+		got, known := breakdown["duration"]
+		if known != tt.known || got != tt.want {
+			t.Errorf("duration score of %d and %v = %f, %v, want %f, %v", tt.runtime, tt.durations, got, known, tt.want, tt.known)
+		}
+	}
+
+	// a matching duration breaks the tie between otherwise equal torrents
+	subs := []models.Subtitle{{Id: 1, Duration: []string{"01:57:10"}}}
+	torrents := []models.Torrent{{Title: "Alien.Theatrical", Runtime: 138}, {Title: "Alien.Directors.Cut", Runtime: 117}}
+	if pair, _ := m.Best(torrents, subs); pair.Torrent.Runtime != 117 {
+		t.Errorf("Best() = %s, want the torrent matching the subtitle duration", pair.Torrent.Title)
+	}
+}
@@ -0,0 +1,101 @@
+package metainfo
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/xochilpili/processor-films/internal/bencode"
+	"github.com/xochilpili/processor-films/internal/magnet"
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+var ErrInvalidTorrent = errors.New("invalid torrent file")
+
+// Parse decodes a .torrent file into the metadata returned by torrent-metadata-api, the infohash
+// is the sha1 of the bencoded info dictionary.
+func Parse(data []byte) (*models.TorrentMetadata, error) {
+	dict, raw, err := bencode.DecodeDict(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTorrent, err)
+	}
+	info, ok := dict["info"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing info dictionary", ErrInvalidTorrent)
+	}
+	name, _ := info["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("%w: missing name", ErrInvalidTorrent)
+	}
+	sum := sha1.Sum(raw["info"])
+
+	var metadata models.TorrentMetadata
+	metadata.Data.Name = name
+	metadata.Data.InfoHash = hex.EncodeToString(sum[:])
+	metadata.Data.Announce = announces(dict)
+	metadata.Data.MagnetUri = magnet.Build(metadata.Data.InfoHash, name, metadata.Data.Announce)
+
+	files, ok := info["files"].([]any)
+	if !ok {
+		// single file torrent
+		length, _ := info["length"].(int64)
+		metadata.Data.Files = []models.MetadataFile{{Name: name, Path: name, Size: int(length)}}
+		return &metadata, nil
+	}
+	for _, f := range files {
+		file, ok := f.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid file entry", ErrInvalidTorrent)
+		}
+		parts, _ := file["path"].([]any)
+		elements := []string{name}
+		for _, part := range parts {
+			if element, ok := part.(string); ok {
+				elements = append(elements, element)
+			}
+		}
+		if len(elements) == 1 {
+			return nil, fmt.Errorf("%w: file without path", ErrInvalidTorrent)
+		}
+		length, _ := file["length"].(int64)
+		metadata.Data.Files = append(metadata.Data.Files, models.MetadataFile{
+			Name: elements[len(elements)-1],
+			Path: path.Join(elements...),
+			Size: int(length),
+		})
+	}
+	return &metadata, nil
+}
+
+// FromMagnet returns the metadata known from the magnet uri alone, it has no files.
+func FromMagnet(m *magnet.Magnet, uri string) *models.TorrentMetadata {
+	var metadata models.TorrentMetadata
+	metadata.Data.Name = m.Name
+	metadata.Data.InfoHash = m.InfoHash
+	metadata.Data.Announce = m.Trackers
+	metadata.Data.MagnetUri = uri
+	return &metadata
+}
+
+// announces returns the trackers of announce-list, or announce when the list is missing.
+func announces(dict map[string]any) []string {
+	var trackers []string
+	seen := map[string]bool{}
+	if tiers, ok := dict["announce-list"].([]any); ok {
+		for _, tier := range tiers {
+			urls, _ := tier.([]any)
+			for _, u := range urls {
+				if tracker, ok := u.(string); ok && !seen[tracker] {
+					seen[tracker] = true
+					trackers = append(trackers, tracker)
+				}
+			}
+		}
+	}
+	if tracker, ok := dict["announce"].(string); ok && !seen[tracker] {
+		trackers = append(trackers, tracker)
+	}
+	return trackers
+}
@@ -0,0 +1,10 @@
+package models
+
+// CacheStats are the counters of the torrent metadata cache since the service started.
+type CacheStats struct {
+	Entries      int     `json:"entries"`
+	MemoryHits   uint64  `json:"memory_hits"`
+	DatabaseHits uint64  `json:"database_hits"`
+	Misses       uint64  `json:"misses"`
+	HitRate      float64 `json:"hit_rate"`
+}
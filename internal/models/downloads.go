@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+const (
+	// DOWNLOAD_ADDED is the state of downloads not yet seen by the download tracker.
+	DOWNLOAD_ADDED = "added"
+	// DOWNLOAD_MISSING is the state of downloads no longer found in the download client.
+	DOWNLOAD_MISSING = "missing"
+)
+
+type FilmDownload struct {
+	OperationType string     `json:"operation_type"`
+	FilmId        int        `json:"film_id"`
+	Hash          string     `json:"hash"`
+	Name          string     `json:"name,omitempty"`
+	State         string     `json:"state"`
+	Progress      float64    `json:"progress"`
+	Ratio         float64    `json:"ratio"`
+	Size          int64      `json:"size"`
+	ContentPath   string     `json:"content_path,omitempty"`
+	AddedAt       *time.Time `json:"added_at,omitempty"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	// Subtitles are the online subtitles placed once the download completes, one per language
+	Subtitles []DownloadSubtitle `json:"subtitles,omitempty"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+// DownloadSubtitle is an online subtitle of a download, Path is set once it was placed and
+// Error when the last attempt to place it failed, Attempts counts the failed placements.
+type DownloadSubtitle struct {
+	SubtitleId int    `json:"subtitle_id"`
+	Language   string `json:"language,omitempty"`
+	Path       string `json:"path,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Attempts   int    `json:"attempts,omitempty"`
+}
@@ -79,6 +79,26 @@ type Torrent struct {
 	Peers         int       `json:"peers"`
 	Size          string    `json:"size"`
 	Magnet        string    `json:"magnet"`
+	// TorrentUrl is the .torrent file url when the provider exposes it, or a data url holding the raw torrent
+	TorrentUrl string `json:"torrent_url,omitempty"`
+	// Runtime of the film in minutes when the provider exposes it
+	Runtime int `json:"runtime,omitempty"`
+}
+
+type SubtitleSource string
+
+const (
+	SUBTITLE_EMBEDDED SubtitleSource = "embedded"
+	SUBTITLE_ONLINE   SubtitleSource = "online"
+	SUBTITLE_NONE     SubtitleSource = "none"
+)
+
+type AddTorrentOptions struct {
+	SavePath   string
+	Category   string
+	Tags       []string
+	Paused     bool
+	Sequential bool
 }
 
 type Subtitle struct {
@@ -89,6 +109,8 @@ type Subtitle struct {
 	Quality     []string `json:"quality"`
 	Resolution  []string `json:"resolution"`
 	Duration    []string `json:"duration"`
+	// Language is the language tag of the subtitle, the requested language when subtitler does not send it
+	Language string `json:"language,omitempty"`
 }
 
 type MetadataFile struct {
@@ -122,10 +144,27 @@ const (
 	POPULAR
 )
 
+var OperationTypes = []OperationType{FESTIVALS, POPULAR}
+
+// ParseOperationType returns the operation type by its name (festivals) or table (films_festivals).
+func ParseOperationType(name string) (OperationType, bool) {
+	for _, opType := range OperationTypes {
+		if name == opType.Name() || name == opType.String() {
+			return opType, true
+		}
+	}
+	return 0, false
+}
+
 func (p OperationType) String() string {
 	return [...]string{"films_festivals", "films_popular"}[p-1]
 }
 
+// Name returns the operation name without the table prefix.
+func (p OperationType) Name() string {
+	return [...]string{"festivals", "popular"}[p-1]
+}
+
 func (p OperationType) EnumIndex() int {
 	return int(p)
 }
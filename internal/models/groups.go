@@ -0,0 +1,7 @@
+package models
+
+// GroupAlias maps a name a release group is written with to its canonical name.
+type GroupAlias struct {
+	Alias string `json:"alias"`
+	Group string `json:"group"`
+}
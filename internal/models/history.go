@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// FilmProcessAttempt records what happened every time a film was processed.
+type FilmProcessAttempt struct {
+	Id             int            `json:"id"`
+	JobId          int            `json:"job_id"`
+	OperationType  string         `json:"operation_type"`
+	FilmId         int            `json:"film_id"`
+	SearchTerm     string         `json:"search_term"`
+	Candidates     int            `json:"candidates"`
+	Torrent        string         `json:"torrent,omitempty"`
+	Score          *float64       `json:"score,omitempty"`
+	SubtitleSource SubtitleSource `json:"subtitle_source"`
+	Outcome        FilmOutcome    `json:"outcome"`
+	Error          string         `json:"error,omitempty"`
+	DurationMs     int64          `json:"duration_ms"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+type JobStatus string
+
+const (
+	JOB_QUEUED    JobStatus = "queued"
+	JOB_RUNNING   JobStatus = "running"
+	JOB_COMPLETED JobStatus = "completed"
+	JOB_FAILED    JobStatus = "failed"
+)
+
+type FilmOutcome string
+
+const (
+	OUTCOME_ADDED        FilmOutcome = "added"
+	OUTCOME_NO_TORRENTS  FilmOutcome = "no_torrents"
+	OUTCOME_NO_SUBTITLES FilmOutcome = "no_subtitles"
+	OUTCOME_NO_MATCH     FilmOutcome = "no_match"
+	OUTCOME_FAILED       FilmOutcome = "failed"
+)
+
+type Job struct {
+	Id            int             `json:"id"`
+	OperationType string          `json:"operation_type"`
+	Provider      string          `json:"provider"`
+	BatchSize     int             `json:"batch_size"`
+	MaxFilms      int             `json:"max_films"`
+	Profile       *QualityProfile `json:"profile,omitempty"`
+	Status        JobStatus       `json:"status"`
+	StartedAt     time.Time       `json:"started_at"`
+	FinishedAt    *time.Time      `json:"finished_at,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	Films         []JobFilm       `json:"films,omitempty"`
+}
+
+// RunOptions zero values fallback to the configured defaults, a MaxFilms of 0 in config means no limit.
+type RunOptions struct {
+	Provider  string
+	Wait      bool
+	BatchSize int
+	MaxFilms  int
+	// Profile is the quality profile name, Resolutions overrides the profile resolutions
+	Profile     string
+	Resolutions []string
+}
+
+type JobFilm struct {
+	FilmId    int         `json:"film_id"`
+	Title     string      `json:"title"`
+	Outcome   FilmOutcome `json:"outcome"`
+	Torrent   string      `json:"torrent,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	ErrorKind string      `json:"error_kind,omitempty"`
+	// Score of the chosen torrent with the score of every scorer
+	Score          float64            `json:"score,omitempty"`
+	ScoreBreakdown map[string]float64 `json:"score_breakdown,omitempty"`
+	CreatedAt      time.Time          `json:"created_at"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// SubtitleLanguage is a wanted subtitle language, Variants are its regional tags in preference order,
+// e.g. es with es-419 before es-ES prefers Latin American over Castilian subtitles.
+type SubtitleLanguage struct {
+	Code     string   `json:"code"`
+	Variants []string `json:"variants,omitempty"`
+}
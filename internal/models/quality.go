@@ -0,0 +1,13 @@
+package models
+
+// QualityProfile restricts and orders the torrents accepted for a film, empty fields do not restrict.
+type QualityProfile struct {
+	Name string `json:"name"`
+	// Resolutions in preference order, each one is searched until candidates are found
+	Resolutions     []string `json:"resolutions"`
+	Codecs          []string `json:"codecs,omitempty"`
+	MinSize         int64    `json:"min_size,omitempty"`
+	MaxSize         int64    `json:"max_size,omitempty"`
+	PreferredGroups []string `json:"preferred_groups,omitempty"`
+	BannedKeywords  []string `json:"banned_keywords,omitempty"`
+}
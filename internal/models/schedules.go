@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type Schedule struct {
+	OperationType string     `json:"operation_type"`
+	Expression    string     `json:"expression"`
+	Enabled       bool       `json:"enabled"`
+	NextRun       *time.Time `json:"next_run,omitempty"`
+	LastRun       *time.Time `json:"last_run,omitempty"`
+	LastJobId     int        `json:"last_job_id,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+}
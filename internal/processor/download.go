@@ -0,0 +1,64 @@
+package processor
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/xochilpili/processor-films/internal/config"
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+var pathReplacer = strings.NewReplacer("/", " ", "\\", " ", ":", " -", "*", "", "?", "", "\"", "", "<", "", ">", "", "|", "")
+
+// downloadOptions merges the global download options with the operation type overrides.
+func (p *Processor) downloadOptions(opType models.OperationType, film models.FilmItem, source models.SubtitleSource) models.AddTorrentOptions {
+	base := p.config.Download
+	var override config.DownloadOptions
+	switch opType {
+	case models.FESTIVALS:
+		override = p.config.FestivalsDownload
+	case models.POPULAR:
+		override = p.config.PopularDownload
+	}
+
+	opts := models.AddTorrentOptions{
+		SavePath:   base.SavePath,
+		Category:   base.Category,
+		Paused:     base.Paused.Value,
+		Sequential: base.Sequential.Value,
+	}
+	if override.SavePath != "" {
+		opts.SavePath = override.SavePath
+	}
+	if override.Category != "" {
+		opts.Category = override.Category
+	}
+	if override.Paused.Set {
+		opts.Paused = override.Paused.Value
+	}
+	if override.Sequential.Set {
+		opts.Sequential = override.Sequential.Value
+	}
+	opts.SavePath = expandSavePath(opts.SavePath, opType, film)
+
+	opts.Tags = append(opts.Tags, base.Tags...)
+	opts.Tags = append(opts.Tags, override.Tags...)
+	opts.Tags = append(opts.Tags, opType.Name(), "subtitles-"+string(source))
+	if film.Provider != "" {
+		opts.Tags = append(opts.Tags, film.Provider)
+	}
+	return opts
+}
+
+func expandSavePath(template string, opType models.OperationType, film models.FilmItem) string {
+	if template == "" {
+		return ""
+	}
+	r := strings.NewReplacer(
+		"{operation}", opType.Name(),
+		"{provider}", pathReplacer.Replace(film.Provider),
+		"{title}", strings.TrimSpace(pathReplacer.Replace(film.Title)),
+		"{year}", strconv.Itoa(film.Year),
+	)
+	return r.Replace(template)
+}
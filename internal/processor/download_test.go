@@ -0,0 +1,75 @@
+package processor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/xochilpili/processor-films/internal/config"
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+func TestExpandSavePath(t *testing.T) {
+	tests := []struct {
+		template string
+		opType   models.OperationType
+		film     models.FilmItem
+		want     string
+	}{
+		{"/films/{operation}/{title} ({year})", models.FESTIVALS, models.FilmItem{Title: "Alien", Year: 1979}, "/films/festivals/Alien (1979)"},
+		{"/films/{operation}/{title} ({year})", models.POPULAR, models.FilmItem{Title: "AC/DC: Let There Be Rock", Year: 1980}, "/films/popular/AC DC - Let There Be Rock (1980)"},
+		{"/films/{title}", models.POPULAR, models.FilmItem{Title: "What Happened to Monday?"}, "/films/What Happened to Monday"},
+		{"/films/{title}", models.POPULAR, models.FilmItem{Title: "Face/Off/"}, "/films/Face Off"},
+		{"/films/{provider}/{title}", models.POPULAR, models.FilmItem{Provider: "yts", Title: "Alien"}, "/films/yts/Alien"},
+		{"/films", models.POPULAR, models.FilmItem{Title: "Alien"}, "/films"},
+		{"", models.POPULAR, models.FilmItem{Title: "Alien"}, ""},
+	}
+	for _, tt := range tests {
+		if got := expandSavePath(tt.template, tt.opType, tt.film); got != tt.want {
+			t.Errorf("expandSavePath(%q, %s, %q) = %q, want %q", tt.template, tt.opType.Name(), tt.film.Title, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadOptions(t *testing.T) {
+	cfg := testConfig()
+	cfg.Download = config.DownloadOptions{
+		SavePath: "/films/{operation}/{title} ({year})",
+		Category: "films",
+		Tags:     []string{"processor"},
+		Paused:   config.OptionalBool{Set: true, Value: true},
+	}
+	cfg.FestivalsDownload = config.DownloadOptions{
+		SavePath:   "/festivals/{title}",
+		Category:   "festivals",
+		Tags:       []string{"cinephile"},
+		Paused:     config.OptionalBool{Set: true, Value: false},
+		Sequential: config.OptionalBool{Set: true, Value: true},
+	}
+	p := newTestProcessor(cfg)
+	film := models.FilmItem{Provider: "yts", Title: "Alien/Aliens", Year: 1979}
+
+	tests := []struct {
+		opType models.OperationType
+		source models.SubtitleSource
+		want   models.AddTorrentOptions
+	}{
+		{models.POPULAR, models.SUBTITLE_ONLINE, models.AddTorrentOptions{
+			SavePath: "/films/popular/Alien Aliens (1979)",
+			Category: "films",
+			Tags:     []string{"processor", "popular", "subtitles-" + string(models.SUBTITLE_ONLINE), "yts"},
+			Paused:   true,
+		}},
+		{models.FESTIVALS, models.SUBTITLE_EMBEDDED, models.AddTorrentOptions{
+			SavePath:   "/festivals/Alien Aliens",
+			Category:   "festivals",
+			Tags:       []string{"processor", "cinephile", "festivals", "subtitles-" + string(models.SUBTITLE_EMBEDDED), "yts"},
+			Paused:     false,
+			Sequential: true,
+		}},
+	}
+	for _, tt := range tests {
+		if got := p.downloadOptions(tt.opType, film, tt.source); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("downloadOptions(%s) = %+v, want %+v", tt.opType.Name(), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/xochilpili/processor-films/internal/magnet"
+	"github.com/xochilpili/processor-films/internal/metainfo"
+	"github.com/xochilpili/processor-films/internal/services"
+)
+
+type ErrorKind string
+
+const (
+	// ERR_TRANSIENT upstream failures, film is left untouched so the next run retries it
+	ERR_TRANSIENT ErrorKind = "transient"
+	// ERR_NOT_FOUND permanent failures for the film, film is marked as attempted
+	ERR_NOT_FOUND ErrorKind = "not_found"
+	// ERR_BAD_RESPONSE upstream answered with an unexpected payload for the film, film is marked as attempted
+	ERR_BAD_RESPONSE ErrorKind = "bad_response"
+	// ERR_DATABASE database failures abort the whole run
+	ERR_DATABASE ErrorKind = "database"
+)
+
+type FilmError struct {
+	Kind   ErrorKind
+	FilmId int
+	Err    error
+}
+
+func (e *FilmError) Error() string {
+	return fmt.Sprintf("film %d: %s error: %v", e.FilmId, e.Kind, e.Err)
+}
+
+func (e *FilmError) Unwrap() error {
+	return e.Err
+}
+
+func newFilmError(kind ErrorKind, filmId int, err error) *FilmError {
+	return &FilmError{Kind: kind, FilmId: filmId, Err: err}
+}
+
+// classify returns the kind of an error, empty for nil errors.
+func classify(err error) ErrorKind {
+	if err == nil {
+		return ""
+	}
+	var filmErr *FilmError
+	if errors.As(err, &filmErr) {
+		return filmErr.Kind
+	}
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		return ERR_NOT_FOUND
+	case errors.Is(err, services.ErrBadResponse), errors.Is(err, magnet.ErrInvalidMagnet), errors.Is(err, metainfo.ErrInvalidTorrent):
+		return ERR_BAD_RESPONSE
+	}
+	// unavailable or rate limited upstreams, timeouts, network and unknown errors, the film is retried later
+	return ERR_TRANSIENT
+}
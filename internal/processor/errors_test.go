@@ -0,0 +1,39 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/xochilpili/processor-films/internal/magnet"
+	"github.com/xochilpili/processor-films/internal/metainfo"
+	"github.com/xochilpili/processor-films/internal/services"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{"nil", nil, ""},
+		{"film error", newFilmError(ERR_DATABASE, 1, errors.New("connection refused")), ERR_DATABASE},
+		{"upstream not found", &services.UpstreamError{Kind: services.ErrNotFound, Upstream: services.TORRENT_API}, ERR_NOT_FOUND},
+		{"upstream bad response", &services.UpstreamError{Kind: services.ErrBadResponse, Upstream: services.TORRENT_API}, ERR_BAD_RESPONSE},
+		{"upstream unavailable", &services.UpstreamError{Kind: services.ErrUpstreamUnavailable, Upstream: services.TORRENT_API}, ERR_TRANSIENT},
+		{"rate limited", &services.UpstreamError{Kind: services.ErrRateLimited, Upstream: services.TORRENT_API}, ERR_TRANSIENT},
+		{"invalid magnet", magnet.ErrInvalidMagnet, ERR_BAD_RESPONSE},
+		{"wrapped invalid magnet", fmt.Errorf("adding torrent: %w", magnet.ErrInvalidMagnet), ERR_BAD_RESPONSE},
+		{"invalid torrent file", fmt.Errorf("%w: missing name", metainfo.ErrInvalidTorrent), ERR_BAD_RESPONSE},
+		{"deadline", context.DeadlineExceeded, ERR_TRANSIENT},
+		{"unknown", errors.New("unknown"), ERR_TRANSIENT},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classify(tt.err); got != tt.want {
+				t.Errorf("classify(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
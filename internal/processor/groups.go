@@ -0,0 +1,42 @@
+package processor
+
+import (
+	"context"
+	"errors"
+
+	"github.com/xochilpili/processor-films/internal/groups"
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+var ErrInvalidGroupAlias = errors.New("alias and group are required")
+
+// loadGroupAliases adds the aliases stored in the database, which win over the configured ones.
+// It runs on connect and on every run so aliases added on other replicas are picked up.
+func (p *Processor) loadGroupAliases(ctx context.Context) error {
+	aliases, err := p.dbService.GetGroupAliases(ctx)
+	if err != nil {
+		p.logger.Err(err).Msg("error while loading group aliases")
+		return err
+	}
+	for _, alias := range aliases {
+		p.groups.Set(alias.Alias, alias.Group)
+	}
+	return nil
+}
+
+func (p *Processor) GetGroupAliases() []models.GroupAlias {
+	return p.groups.List()
+}
+
+// AddGroupAlias stores the alias and applies it to the following matches.
+func (p *Processor) AddGroupAlias(ctx context.Context, alias models.GroupAlias) (*models.GroupAlias, error) {
+	alias.Alias, alias.Group = groups.Normalize(alias.Alias), groups.Normalize(alias.Group)
+	if alias.Alias == "" || alias.Group == "" || alias.Alias == alias.Group {
+		return nil, ErrInvalidGroupAlias
+	}
+	if err := p.dbService.SaveGroupAlias(ctx, alias); err != nil {
+		return nil, err
+	}
+	p.groups.Set(alias.Alias, alias.Group)
+	return &alias, nil
+}
@@ -0,0 +1,63 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+func TestAddGroupAlias(t *testing.T) {
+	tests := []struct {
+		name    string
+		alias   models.GroupAlias
+		want    *models.GroupAlias
+		wantErr error
+	}{
+		{"normalized", models.GroupAlias{Alias: "[EVO.MX]", Group: "Evolution"}, &models.GroupAlias{Alias: "evo", Group: "evolution"}, nil},
+		{"empty alias", models.GroupAlias{Alias: "", Group: "evolution"}, nil, ErrInvalidGroupAlias},
+		{"empty group", models.GroupAlias{Alias: "evo", Group: "  "}, nil, ErrInvalidGroupAlias},
+		{"only brackets", models.GroupAlias{Alias: "[]", Group: "evolution"}, nil, ErrInvalidGroupAlias},
+		{"same group", models.GroupAlias{Alias: "YTS.MX", Group: "yts-mx"}, nil, ErrInvalidGroupAlias},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProcessor(testConfig())
+			db := p.dbService.(*fakeDatabase)
+			got, err := p.AddGroupAlias(context.Background(), tt.alias)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AddGroupAlias(%+v) error = %v, want %v", tt.alias, err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(db.aliases) != 0 {
+					t.Errorf("invalid alias saved: %+v", db.aliases)
+				}
+				return
+			}
+			if *got != *tt.want {
+				t.Errorf("AddGroupAlias(%+v) = %+v, want %+v", tt.alias, *got, *tt.want)
+			}
+			if len(db.aliases) != 1 || db.aliases[0] != *tt.want {
+				t.Errorf("saved aliases = %+v, want %+v", db.aliases, *tt.want)
+			}
+			if canonical := p.groups.Canonical(tt.alias.Alias); canonical != tt.want.Group {
+				t.Errorf("Canonical(%q) = %q, want %q", tt.alias.Alias, canonical, tt.want.Group)
+			}
+		})
+	}
+}
+
+func TestLoadGroupAliases(t *testing.T) {
+	cfg := testConfig()
+	cfg.GroupAliases = map[string]string{"evo": "evolution"}
+	p := newTestProcessor(cfg)
+	db := p.dbService.(*fakeDatabase)
+	db.aliases = []models.GroupAlias{{Alias: "evo", Group: "evo-team"}}
+	if err := p.loadGroupAliases(context.Background()); err != nil {
+		t.Fatalf("loadGroupAliases: %v", err)
+	}
+	if got := p.groups.Canonical("EVO"); got != "evo-team" {
+		t.Errorf("stored alias should win over the configured one, got %q", got)
+	}
+}
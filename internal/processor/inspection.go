@@ -0,0 +1,87 @@
+package processor
+
+import (
+	"context"
+	"sync"
+
+	"github.com/xochilpili/processor-films/internal/magnet"
+	"github.com/xochilpili/processor-films/internal/models"
+	"github.com/xochilpili/processor-films/internal/subtitles"
+)
+
+// Inspection is the result of inspecting the files of a torrent, Err is set when its metadata
+// could not be fetched, the torrent is then ranked without embedded subtitles.
+type Inspection struct {
+	Torrent   models.Torrent
+	Files     int
+	Tracks    []subtitles.Track
+	Languages subtitles.Languages
+	Err       error
+}
+
+// HasSubtitles returns true when subtitle files were found in the torrent.
+func (i Inspection) HasSubtitles() bool {
+	return len(i.Tracks) > 0
+}
+
+// inspectTorrents fetches the metadata of every torrent concurrently and detects the subtitles of
+// their files, the report keeps the order of torrents. Concurrency is bounded by the metadata
+// upstream limiter.
+func (p *Processor) inspectTorrents(ctx context.Context, torrents []models.Torrent) []Inspection {
+	inspections := make([]Inspection, len(torrents))
+	var wg sync.WaitGroup
+	for i := range torrents {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			inspections[i] = p.inspectTorrent(ctx, torrents[i])
+		}(i)
+	}
+	wg.Wait()
+
+	var failed int
+	for _, inspection := range inspections {
+		if inspection.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		p.logger.Warn().Msgf("metadata of %d out of %d torrents could not be inspected", failed, len(torrents))
+	}
+	return inspections
+}
+
+func (p *Processor) inspectTorrent(ctx context.Context, torrent models.Torrent) Inspection {
+	inspection := Inspection{Torrent: torrent, Languages: subtitles.Languages{}}
+	metadata, err := p.torrentMetadata(ctx, &torrent)
+	if err != nil {
+		p.logger.Err(err).Msgf("error while receiving metadata for torrent: %s", torrent.Title)
+		inspection.Err = err
+		return inspection
+	}
+	inspection.Files = len(metadata.Data.Files)
+	inspection.Tracks = subtitles.DetectTracks(metadata.Data.Files)
+	inspection.Languages = subtitles.TrackLanguages(inspection.Tracks)
+	p.logger.Info().Msgf("%d total files found in torrent's metadata: %s, subtitle languages: %v", inspection.Files, torrent.Title, inspection.Languages)
+	return inspection
+}
+
+// torrentMetadata returns the cached metadata of the torrent, fetching it when it is not cached.
+// Torrents whose magnet has no valid infohash are not cached.
+func (p *Processor) torrentMetadata(ctx context.Context, torrent *models.Torrent) (*models.TorrentMetadata, error) {
+	infoHash, err := magnet.InfoHash(torrent.Magnet)
+	if err == nil {
+		if metadata, ok := p.metadata.Get(ctx, infoHash); ok {
+			return metadata, nil
+		}
+	}
+	metadata, err := p.apiService.GetTorrentMetadata(ctx, torrent)
+	if err != nil {
+		return nil, err
+	}
+	// metadata without files (e.g. built from the magnet alone) is not cached so it is looked up again
+	if infoHash != "" && len(metadata.Data.Files) > 0 {
+		p.metadata.Set(ctx, infoHash, metadata)
+	}
+	return metadata, nil
+}
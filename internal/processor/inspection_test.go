@@ -0,0 +1,66 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+func TestInspectTorrents(t *testing.T) {
+	p := newTestProcessor(testConfig())
+	failure := errors.New("metadata unavailable")
+	p.apiService.(*fakeApi).metadata = func(ctx context.Context, torrent *models.Torrent) (*models.TorrentMetadata, error) {
+		switch torrent.Title {
+		case "failing":
+			return nil, failure
+		case "slow":
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		metadata := &models.TorrentMetadata{}
+		metadata.Data.Files = []models.MetadataFile{
+			{Path: "Alien.1979/Alien.1979.mkv"},
+			{Path: "Alien.1979/Subs/2_Spanish.srt"},
+		}
+		return metadata, nil
+	}
+	torrents := []models.Torrent{
+		{Title: "failing", Magnet: "magnet:?xt=urn:btih:1111111111111111111111111111111111111111"},
+		{Title: "slow", Magnet: "magnet:?xt=urn:btih:2222222222222222222222222222222222222222"},
+		{Title: "inspected", Magnet: "magnet:?xt=urn:btih:3333333333333333333333333333333333333333"},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	startedAt := time.Now()
+	inspections := p.inspectTorrents(ctx, torrents)
+	if elapsed := time.Since(startedAt); elapsed > time.Second {
+		t.Errorf("inspectTorrents() took %s, the context deadline was not honored", elapsed)
+	}
+
+	if len(inspections) != len(torrents) {
+		t.Fatalf("inspections = %d, want %d", len(inspections), len(torrents))
+	}
+	for i, inspection := range inspections {
+		if inspection.Torrent.Title != torrents[i].Title {
+			t.Errorf("inspection %d = %s, want %s", i, inspection.Torrent.Title, torrents[i].Title)
+		}
+	}
+	if !errors.Is(inspections[0].Err, failure) {
+		t.Errorf("failing inspection error = %v", inspections[0].Err)
+	}
+	if !errors.Is(inspections[1].Err, context.DeadlineExceeded) {
+		t.Errorf("slow inspection error = %v", inspections[1].Err)
+	}
+	if inspected := inspections[2]; inspected.Err != nil || inspected.Files != 2 || !inspected.HasSubtitles() || !inspected.Languages.Has("es") {
+		t.Errorf("inspected = %+v", inspected)
+	}
+	for _, inspection := range inspections[:2] {
+		if inspection.HasSubtitles() || inspection.Languages == nil {
+			t.Errorf("%s inspection = %+v, want no subtitles", inspection.Torrent.Title, inspection)
+		}
+	}
+}
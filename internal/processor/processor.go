@@ -2,33 +2,72 @@ package processor
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"path"
-	"strings"
+	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
+	"github.com/xochilpili/processor-films/internal/cache"
 	"github.com/xochilpili/processor-films/internal/config"
 	"github.com/xochilpili/processor-films/internal/database"
+	"github.com/xochilpili/processor-films/internal/groups"
+	"github.com/xochilpili/processor-films/internal/matcher"
 	"github.com/xochilpili/processor-films/internal/models"
+	"github.com/xochilpili/processor-films/internal/ranking"
 	"github.com/xochilpili/processor-films/internal/services"
+	"github.com/xochilpili/processor-films/internal/subtitles"
 	"github.com/xochilpili/processor-films/internal/utils"
 )
 
 type ApiService interface {
 	FetchTorrents(ctx context.Context, params models.FilterParams) ([]models.Torrent, error)
-	AddTorrent(ctx context.Context, magnetLink string) error
-	GetSubtitles(ctx context.Context, title string) ([]models.Subtitle, error)
+	GetSubtitles(ctx context.Context, title string, language string) ([]models.Subtitle, error)
 	GetTorrentMetadata(ctx context.Context, torrent *models.Torrent) (*models.TorrentMetadata, error)
+	DownloadSubtitle(ctx context.Context, id int) ([]byte, string, error)
+	ResetBreakers()
+}
+
+type DownloadClient interface {
+	AddTorrent(ctx context.Context, magnetLink string, opts models.AddTorrentOptions) (string, error)
+	Torrents(ctx context.Context, hashes []string) ([]models.BitTorrent, error)
+	ResetBreakers()
 }
 
 type DatabaseService interface {
 	Connect() error
 	Close() error
-	GetFilms(ctx context.Context, table string, columns []string, provider string) ([]models.FilmItem, error)
-	GetOlderFilms(ctx context.Context, table string) ([]models.FilmItem, error)
-	UpdateProcess(ctx context.Context, table string, id int)
-	ProcessedFilm(ctx context.Context, table string, id int)
+	GetFilms(ctx context.Context, table string, columns []string, provider string, afterId int, limit int) ([]models.FilmItem, error)
+	GetOlderFilms(ctx context.Context, table string, afterId int, limit int) ([]models.FilmItem, error)
+	UpdateProcess(ctx context.Context, table string, id int) error
+	ProcessedFilm(ctx context.Context, table string, id int, hash string) error
+	CreateJob(ctx context.Context, job *models.Job) error
+	UpdateJob(ctx context.Context, job *models.Job) error
+	InterruptJobs(ctx context.Context, operationType string, reason string) (int64, error)
+	AddJobFilm(ctx context.Context, jobId int, film *models.JobFilm) error
+	GetJobs(ctx context.Context, limit int) ([]models.Job, error)
+	GetJob(ctx context.Context, id int) (*models.Job, error)
+	AcquireLock(ctx context.Context, name string, wait bool) (func(), error)
+	GetPendingDownloads(ctx context.Context, table string) ([]models.FilmDownload, error)
+	SaveDownload(ctx context.Context, download *models.FilmDownload) error
+	GetFilmDownloads(ctx context.Context, filmId int, table string) ([]models.FilmDownload, error)
+	SetDownloadSubtitles(ctx context.Context, download *models.FilmDownload) error
+	GetDownloadsAwaitingSubtitles(ctx context.Context, maxAttempts int) ([]models.FilmDownload, error)
+	UpdateDownloadSubtitle(ctx context.Context, download *models.FilmDownload, subtitle *models.DownloadSubtitle) error
+	GetCachedMetadata(ctx context.Context, infoHash string, maxAge time.Duration) (*models.TorrentMetadata, time.Time, error)
+	SaveCachedMetadata(ctx context.Context, infoHash string, metadata *models.TorrentMetadata) error
+	GetGroupAliases(ctx context.Context) ([]models.GroupAlias, error)
+	SaveGroupAlias(ctx context.Context, alias models.GroupAlias) error
+	AddFilmAttempt(ctx context.Context, attempt *models.FilmProcessAttempt) error
+	GetFilmAttempts(ctx context.Context, filmId int, table string) ([]models.FilmProcessAttempt, error)
+	FilmExists(ctx context.Context, table string, id int) (bool, error)
+}
+
+var ErrRunInProgress = errors.New("a run for this operation type is already in progress")
+var ErrFilmNotFound = errors.New("film not found")
+
+type Ranker interface {
+	Rank(candidates []ranking.Candidate) []ranking.Ranked
 }
 
 type Processor struct {
@@ -36,199 +75,612 @@ type Processor struct {
 	logger     *zerolog.Logger
 	dbService  DatabaseService
 	apiService ApiService
+	downloader DownloadClient
+	ranker     Ranker
+	metadata   *cache.Metadata
+	groups     *groups.Aliases
+	matcher    *matcher.Matcher
+	locks      map[models.OperationType]*sync.Mutex
 }
 
 func New(config *config.Config, logger *zerolog.Logger) *Processor {
 	apiService := services.NewApi(config, logger)
 	db := database.New(config, logger)
+	aliases := groups.New(config.GroupAliases)
 	return &Processor{
 		config:     config,
 		logger:     logger,
 		dbService:  db,
 		apiService: apiService,
+		downloader: services.NewDownloadClient(config, logger),
+		ranker:     ranking.New(config, logger, aliases),
+		metadata:   cache.New(config, logger, db),
+		groups:     aliases,
+		matcher:    matcher.New(aliases),
+		locks: map[models.OperationType]*sync.Mutex{
+			models.FESTIVALS: {},
+			models.POPULAR:   {},
+		},
 	}
 }
 
-func (p *Processor) Process(ctx context.Context, opType models.OperationType, provider string) error {
-	err := p.dbService.Connect()
-	if err != nil {
-		p.logger.Fatal().Err(err).Msg("error while connecting to db")
+func (p *Processor) Connect() error {
+	if err := p.dbService.Connect(); err != nil {
 		return err
 	}
-	defer p.dbService.Close()
-
-	films, err := p.dbService.GetOlderFilms(ctx, opType.String())
-	if err != nil {
-		p.logger.Fatal().Err(err).Msgf("error while getting older %s films from db", opType.String())
+	ctx := context.Background()
+	if err := p.interruptJobs(ctx); err != nil {
 		return err
 	}
+	return p.loadGroupAliases(ctx)
+}
 
-	if len(films) == 0 {
-		films, err = p.dbService.GetFilms(ctx, opType.String(), []string{"id", "provider", "title", "year"}, provider)
+// interruptJobs fails the jobs left running or queued by a crash or redeploy. Operation types whose
+// lock is held are running in another replica, their jobs are left untouched.
+func (p *Processor) interruptJobs(ctx context.Context) error {
+	for _, opType := range models.OperationTypes {
+		release, err := p.lock(ctx, opType, false)
+		if errors.Is(err, ErrRunInProgress) {
+			continue
+		}
 		if err != nil {
-			p.logger.Fatal().Err(err).Msgf("error while getting all %s films from db", opType.String())
 			return err
 		}
+		n, err := p.dbService.InterruptJobs(ctx, opType.String(), "interrupted")
+		release()
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			p.logger.Warn().Msgf("%d %s jobs interrupted by a previous shutdown marked as failed", n, opType.String())
+		}
 	}
+	return nil
+}
 
-	for _, film := range films {
+func (p *Processor) Close() error {
+	return p.dbService.Close()
+}
 
-		if film.Provider == "yts" {
-			provider = film.Provider
-		} else {
-			provider = "all"
+func (p *Processor) MetadataCacheStats() models.CacheStats {
+	return p.metadata.Stats()
+}
+
+// Run creates a job record for the operation and process it in background. Only one run per operation
+// type is allowed across replicas, when opts.Wait is true the job is queued until the running one finishes,
+// otherwise ErrRunInProgress is returned. The returned job is a copy, the running one is only updated
+// by its worker.
+func (p *Processor) Run(ctx context.Context, opType models.OperationType, opts models.RunOptions) (*models.Job, error) {
+	job := &models.Job{
+		OperationType: opType.String(),
+		Provider:      opts.Provider,
+		Status:        models.JOB_RUNNING,
+		BatchSize:     opts.BatchSize,
+		MaxFilms:      opts.MaxFilms,
+	}
+	if job.Provider == "" {
+		job.Provider = "all"
+	}
+	if job.BatchSize <= 0 {
+		job.BatchSize = p.config.BatchSize
+	}
+	if job.MaxFilms <= 0 {
+		job.MaxFilms = p.config.MaxFilmsPerRun
+	}
+	profile, err := p.profile(opType, opts)
+	if err != nil {
+		return nil, err
+	}
+	job.Profile = profile
+
+	if opts.Wait {
+		job.Status = models.JOB_QUEUED
+		err = p.dbService.CreateJob(ctx, job)
+		if err != nil {
+			return nil, err
 		}
+		// the worker keeps updating job, the caller gets the state at creation
+		created := *job
+		go func() {
+			ctx := context.Background()
+			release, err := p.lock(ctx, opType, true)
+			if err != nil {
+				p.finishJob(ctx, job, err)
+				return
+			}
+			defer release()
+			job.Status = models.JOB_RUNNING
+			p.dbService.UpdateJob(ctx, job)
+			p.Process(ctx, job, opType)
+		}()
+		return &created, nil
+	}
 
-		var title string
-		if opType.EnumIndex() == 1 {
-			title = fmt.Sprintf("%s %d", film.Title, film.Year)
-		} else {
-			title = film.Title
+	release, err := p.lock(ctx, opType, false)
+	if err != nil {
+		return nil, err
+	}
+	err = p.dbService.CreateJob(ctx, job)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	created := *job
+	go func() {
+		defer release()
+		p.Process(context.Background(), job, opType)
+	}()
+	return &created, nil
+}
+
+// lock acquires the in-process lock for the operation type and then the database advisory lock,
+// so replicas sharing the database do not process the same operation type concurrently.
+func (p *Processor) lock(ctx context.Context, opType models.OperationType, wait bool) (func(), error) {
+	mu := p.locks[opType]
+	if wait {
+		mu.Lock()
+	} else if !mu.TryLock() {
+		return nil, ErrRunInProgress
+	}
+
+	release, err := p.dbService.AcquireLock(ctx, opType.String(), wait)
+	if err != nil {
+		mu.Unlock()
+		if errors.Is(err, database.ErrLockNotAcquired) {
+			return nil, ErrRunInProgress
 		}
+		p.logger.Err(err).Msgf("error while acquiring lock for %s", opType.String())
+		return nil, err
+	}
+	return func() {
+		release()
+		mu.Unlock()
+	}, nil
+}
 
-		p.logger.Info().Msgf("processing film: %s, type: %s", title, opType.String())
+func (p *Processor) GetJobs(ctx context.Context, limit int) ([]models.Job, error) {
+	return p.dbService.GetJobs(ctx, limit)
+}
 
-		torrentItems, err := p.apiService.FetchTorrents(ctx, models.FilterParams{Provider: provider, Term: title, Resolution: "720p"})
-		if err != nil {
-			p.logger.Fatal().Err(err).Msgf("error while getting torrents for: %s", title)
-			return err
+func (p *Processor) GetJob(ctx context.Context, id int) (*models.Job, error) {
+	return p.dbService.GetJob(ctx, id)
+}
+
+func (p *Processor) Process(ctx context.Context, job *models.Job, opType models.OperationType) (err error) {
+	defer func() {
+		// a panic while processing must not take down the whole service
+		if r := recover(); r != nil {
+			p.logger.Error().Msgf("panic while processing %s job %d: %v", opType.String(), job.Id, r)
+			err = fmt.Errorf("panic while processing: %v", r)
 		}
+		p.finishJob(ctx, job, err)
+	}()
 
-		if len(torrentItems) == 0 {
-			p.logger.Info().Msgf("no torrents found for: %s", title)
-			p.dbService.UpdateProcess(ctx, opType.String(), film.Id)
-			continue
+	// a failing upstream is short-circuited for the rest of the run only
+	p.apiService.ResetBreakers()
+	p.downloader.ResetBreakers()
+	// aliases from other replicas, the configured ones are kept when loading fails
+	p.loadGroupAliases(ctx)
+
+	table := opType.String()
+	sources := []struct {
+		name  string
+		fetch func(afterId int, limit int) ([]models.FilmItem, error)
+	}{
+		{"older", func(afterId int, limit int) ([]models.FilmItem, error) {
+			return p.dbService.GetOlderFilms(ctx, table, afterId, limit)
+		}},
+		{"new", func(afterId int, limit int) ([]models.FilmItem, error) {
+			return p.dbService.GetFilms(ctx, table, []string{"id", "provider", "title", "year"}, job.Provider, afterId, limit)
+		}},
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var total, consecutiveErrors int
+	for _, source := range sources {
+		// keyset pagination by id, films updated while processing do not shift the following batches
+		afterId := 0
+		for {
+			if job.MaxFilms > 0 && total >= job.MaxFilms {
+				p.logger.Info().Msgf("max films per run reached (%d) for job %d", job.MaxFilms, job.Id)
+				p.logger.Info().Msgf("processed %d items", total)
+				return nil
+			}
+			limit := job.BatchSize
+			if job.MaxFilms > 0 && job.MaxFilms-total < limit {
+				limit = job.MaxFilms - total
+			}
+
+			films, err := source.fetch(afterId, limit)
+			if err != nil {
+				p.logger.Err(err).Msgf("error while getting %s %s films from db", source.name, table)
+				return fmt.Errorf("error while getting %s films: %w", source.name, err)
+			}
+			if len(films) == 0 {
+				break
+			}
+			if len(films) > limit {
+				films = films[:limit]
+			}
+			p.logger.Info().Msgf("processing batch of %d %s %s films after id: %d", len(films), source.name, table, afterId)
+			afterId = films[len(films)-1].Id
+			total += len(films)
+
+			// in-flight films are still recorded when the run is aborted
+			var abortErr error
+			for r := range p.processBatch(runCtx, job, opType, films) {
+				if r.err != nil {
+					r.result.Outcome = models.OUTCOME_FAILED
+					r.result.Error = r.err.Error()
+					r.result.ErrorKind = string(classify(r.err))
+					p.logger.Err(r.err).Msgf("error while processing film: %s, kind: %s", r.result.Title, r.result.ErrorKind)
+				}
+				p.recordFilm(ctx, job, r.result)
+				p.recordAttempt(ctx, r)
+				if abortErr != nil {
+					continue
+				}
+
+				switch classify(r.err) {
+				case "":
+					consecutiveErrors = 0
+				case ERR_DATABASE:
+					abortErr = r.err
+				case ERR_NOT_FOUND, ERR_BAD_RESPONSE:
+					consecutiveErrors = 0
+					if err := p.dbService.UpdateProcess(ctx, table, r.film.Id); err != nil {
+						abortErr = newFilmError(ERR_DATABASE, r.film.Id, err)
+					}
+				case ERR_TRANSIENT:
+					consecutiveErrors++
+					if p.config.MaxConsecutiveErrors > 0 && consecutiveErrors >= p.config.MaxConsecutiveErrors {
+						abortErr = fmt.Errorf("aborting after %d consecutive upstream errors: %w", consecutiveErrors, r.err)
+					}
+				}
+				if abortErr != nil {
+					cancel()
+				}
+			}
+			if abortErr != nil {
+				return abortErr
+			}
 		}
+	}
+	p.logger.Info().Msgf("processed %d items", total)
+	return nil
+}
 
-		torrent, strFile, spa := p.hasFileSubtitles(torrentItems)
-		if spa {
-			// torrent has spanish subtitles
-			p.apiService.AddTorrent(ctx, torrent.Magnet)
-			p.dbService.ProcessedFilm(ctx, opType.String(), film.Id)
-			continue
+type filmResult struct {
+	film    models.FilmItem
+	result  *models.JobFilm
+	attempt *models.FilmProcessAttempt
+	err     error
+}
+
+// processBatch processes the films with a pool of workers, the returned channel is closed once every
+// dispatched film is done. No more films are dispatched after ctx is cancelled.
+func (p *Processor) processBatch(ctx context.Context, job *models.Job, opType models.OperationType, films []models.FilmItem) <-chan filmResult {
+	workers := p.config.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	queue := make(chan models.FilmItem)
+	results := make(chan filmResult)
+
+	go func() {
+		defer close(queue)
+		for _, film := range films {
+			select {
+			case queue <- film:
+			case <-ctx.Done():
+				return
+			}
 		}
+	}()
 
-		subs, err := p.apiService.GetSubtitles(ctx, title)
-		if err != nil {
-			p.logger.Err(err).Msgf("error while fetching subtitles for %s", title)
-			continue
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for film := range queue {
+				attempt := &models.FilmProcessAttempt{
+					JobId:          job.Id,
+					OperationType:  opType.String(),
+					FilmId:         film.Id,
+					SubtitleSource: models.SUBTITLE_NONE,
+				}
+				startedAt := time.Now()
+				result, err := p.safeProcessFilm(ctx, job, opType, film, attempt)
+				attempt.DurationMs = time.Since(startedAt).Milliseconds()
+				results <- filmResult{film: film, result: result, attempt: attempt, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// safeProcessFilm processes the film recovering from panics, a panic is returned as the film error so
+// the worker keeps going with the rest of the batch.
+func (p *Processor) safeProcessFilm(ctx context.Context, job *models.Job, opType models.OperationType, film models.FilmItem, attempt *models.FilmProcessAttempt) (result *models.JobFilm, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.logger.Error().Msgf("panic while processing film %d: %v", film.Id, r)
+			result = &models.JobFilm{FilmId: film.Id, Title: film.Title}
+			err = fmt.Errorf("panic while processing film %d: %v", film.Id, r)
+		}
+	}()
+	return p.processFilm(ctx, job, opType, film, attempt)
+}
+
+// processFilm searches and adds the best torrent for the film, returned errors are *FilmError or upstream errors.
+// The attempt is filled with the search details as the film goes through the pipeline.
+func (p *Processor) processFilm(ctx context.Context, job *models.Job, opType models.OperationType, film models.FilmItem, attempt *models.FilmProcessAttempt) (*models.JobFilm, error) {
+	var provider string
+	if film.Provider == "yts" {
+		provider = film.Provider
+	} else {
+		provider = "all"
+	}
+
+	var title string
+	if opType.EnumIndex() == 1 {
+		title = fmt.Sprintf("%s %d", film.Title, film.Year)
+	} else {
+		title = film.Title
+	}
+
+	result := &models.JobFilm{FilmId: film.Id, Title: title}
+	attempt.SearchTerm = title
+
+	p.logger.Info().Msgf("processing film: %s, type: %s", title, opType.String())
+
+	torrentItems, err := p.searchTorrents(ctx, job.Profile, film, provider, title)
+	if err != nil {
+		return result, err
+	}
+	attempt.Candidates = len(torrentItems)
+
+	if len(torrentItems) == 0 {
+		p.logger.Info().Msgf("no torrents found for: %s", title)
+		if err := p.dbService.UpdateProcess(ctx, opType.String(), film.Id); err != nil {
+			return result, newFilmError(ERR_DATABASE, film.Id, err)
+		}
+		result.Outcome = models.OUTCOME_NO_TORRENTS
+		return result, nil
+	}
+
+	wanted := p.config.SubtitleLanguages
+	inspections := p.inspectTorrents(ctx, torrentItems)
+
+	subs, err := p.getSubtitles(ctx, title, wanted)
+	if err != nil {
+		if !utils.Some(inspections, func(i Inspection) bool { return i.HasSubtitles() }) {
+			return result, err
+		}
+		// torrent files subtitles are enough to keep going
+		p.logger.Err(err).Msgf("error while fetching subtitles for %s, ranking without online subtitles", title)
+	}
+
+	candidates := make([]ranking.Candidate, 0, len(inspections))
+	for _, inspection := range inspections {
+		c := ranking.Candidate{
+			Torrent:           inspection.Torrent,
+			Profile:           job.Profile,
+			Wanted:            wanted,
+			Languages:         inspection.Languages,
+			EmbeddedSubtitles: inspection.HasSubtitles(),
 		}
+		c.Subtitles, c.SubtitleMatch = p.matchSubtitles(inspection.Torrent, subs, wanted, c.Languages)
+		candidates = append(candidates, c)
+	}
+
+	p.logger.Debug().Msgf("film: %s, torrents: %+v", title, torrentItems)
 
+	var best *ranking.Ranked
+	for _, rc := range p.ranker.Rank(candidates) {
+		if rc.Satisfied() {
+			best = &rc
+			break
+		}
+	}
+	if best == nil {
 		if len(subs) == 0 {
-			if strFile {
-				p.apiService.AddTorrent(ctx, torrent.Magnet)
-				p.dbService.ProcessedFilm(ctx, opType.String(), film.Id)
-				p.logger.Info().Msgf("torrent %s added with file subtitles", torrent.Title)
-			}
 			// no torrent files and no subtitles
 			p.logger.Info().Msgf("no subtitles found for %s", title)
-			continue
+			result.Outcome = models.OUTCOME_NO_SUBTITLES
+			return result, nil
 		}
-
-		subTorrent := p.matchSubtitles(torrentItems, subs)
-
-		if subTorrent == nil {
-			// add torrent from file and continue
+		if pair, ok := p.matcher.Best(torrentItems, subs); ok {
+			p.logger.Info().Msgf("no online subtitles matches for %s, best pair %s with %s scored %.2f", title, pair.Torrent.Title, pair.Subtitle.Title, pair.Score)
+		} else {
 			p.logger.Info().Msgf("no online subtitles matches for %s", title)
-			if strFile {
-				p.apiService.AddTorrent(ctx, torrent.Magnet)
-				p.dbService.ProcessedFilm(ctx, opType.String(), film.Id)
-				p.logger.Info().Msgf("torrent %s added with file subtitles", torrent.Title)
-			}
-			continue
 		}
+		result.Outcome = models.OUTCOME_NO_MATCH
+		return result, nil
+	}
 
-		// add sub-torrent and continue
-		p.apiService.AddTorrent(ctx, subTorrent.Magnet)
-		p.dbService.ProcessedFilm(ctx, opType.String(), film.Id)
-		p.logger.Info().Msgf("torrent %s added with matched online subtitles", subTorrent.Title)
+	result.Score = best.Score.Total
+	result.ScoreBreakdown = best.Score.Breakdown
+	attempt.Torrent = best.Torrent.Title
+	attempt.Score = &best.Score.Total
+	if !best.EmbeddedSatisfied() && len(best.Subtitles) > 0 {
+		p.logger.Info().Msgf("torrent %s added with %d matched online subtitles, score: %.3f", best.Torrent.Title, len(best.Subtitles), best.Score.Total)
+		attempt.SubtitleSource = models.SUBTITLE_ONLINE
+		return result, p.addTorrent(ctx, opType, film, &best.Torrent, models.SUBTITLE_ONLINE, best.Subtitles, result)
+	}
+	p.logger.Info().Msgf("torrent %s added with file subtitles, score: %.3f", best.Torrent.Title, best.Score.Total)
+	attempt.SubtitleSource = models.SUBTITLE_EMBEDDED
+	return result, p.addTorrent(ctx, opType, film, &best.Torrent, models.SUBTITLE_EMBEDDED, nil, result)
+}
 
-		// for debug proposes
-		if p.config.Debug {
-			fmt.Printf("film: %s\n", title)
-			out, _ := json.MarshalIndent(torrentItems, "", "\t")
-			fmt.Println(string(out))
+// addTorrent adds the torrent to the download client, subs are the matched online subtitles if any,
+// which are placed next to the film once the download completes.
+func (p *Processor) addTorrent(ctx context.Context, opType models.OperationType, film models.FilmItem, torrent *models.Torrent, source models.SubtitleSource, subs []models.Subtitle, result *models.JobFilm) error {
+	hash, err := p.downloader.AddTorrent(ctx, torrent.Magnet, p.downloadOptions(opType, film, source))
+	if err != nil && !errors.Is(err, services.ErrDuplicateTorrent) {
+		return err
+	}
+	// film is only marked as processed once the download client accepted the torrent
+	if err := p.dbService.ProcessedFilm(ctx, opType.String(), film.Id, hash); err != nil {
+		return newFilmError(ERR_DATABASE, film.Id, err)
+	}
+	if len(subs) > 0 && hash != "" {
+		download := &models.FilmDownload{
+			OperationType: opType.String(),
+			FilmId:        film.Id,
+			Hash:          hash,
+			State:         models.DOWNLOAD_ADDED,
+		}
+		for _, subtitle := range subs {
+			download.Subtitles = append(download.Subtitles, models.DownloadSubtitle{SubtitleId: subtitle.Id, Language: subtitle.Language})
+		}
+		if err := p.dbService.SetDownloadSubtitles(ctx, download); err != nil {
+			return newFilmError(ERR_DATABASE, film.Id, err)
 		}
 	}
-	p.logger.Info().Msgf("processed %d items", len(films))
+	result.Outcome = models.OUTCOME_ADDED
+	result.Torrent = torrent.Title
 	return nil
 }
 
-func (p *Processor) matchSubtitles(torrents []models.Torrent, subs []models.Subtitle) *models.Torrent {
-	var partialMatch bool
-	var torr *models.Torrent
-	for _, torrent := range torrents {
-		ok := utils.Some(subs, func(s models.Subtitle) bool {
-			resOk := utils.Some(s.Resolution, func(res string) bool {
-				if res != "" && res == torrent.Resolution {
-					return true
-				}
-				return false
-			})
-			qOk := utils.Some(s.Quality, func(qa string) bool {
-				if qa != "" && qa == torrent.Quality {
-					return true
-				}
-				return false
-			})
-			gOk := utils.Some(s.Group, func(g string) bool {
-				// subtitles are based on whatever user's add, some of them uses yify instead of yts
-				if g == "yify" && torrent.Group == "yts" {
-					return true
-				}
-				if g != "" && g == torrent.Group {
-					return true
-				}
-				if strings.Contains(strings.ToLower(g), strings.ToLower(torrent.Group)) {
-					return true
-				}
-				return false
-			})
+func (p *Processor) recordFilm(ctx context.Context, job *models.Job, film *models.JobFilm) {
+	if err := p.dbService.AddJobFilm(ctx, job.Id, film); err != nil {
+		return
+	}
+	job.Films = append(job.Films, *film)
+}
+
+// recordAttempt stores the process attempt of the film with the outcome of its job film.
+func (p *Processor) recordAttempt(ctx context.Context, r filmResult) {
+	r.attempt.Outcome = r.result.Outcome
+	r.attempt.Error = r.result.Error
+	p.dbService.AddFilmAttempt(ctx, r.attempt)
+}
+
+// GetFilmHistory returns every process attempt of the film, opType filters by operation type when not nil.
+// ErrFilmNotFound is returned when the film has no attempts because it does not exist.
+func (p *Processor) GetFilmHistory(ctx context.Context, filmId int, opType *models.OperationType) ([]models.FilmProcessAttempt, error) {
+	var table string
+	opTypes := models.OperationTypes
+	if opType != nil {
+		table = opType.String()
+		opTypes = []models.OperationType{*opType}
+	}
+	attempts, err := p.dbService.GetFilmAttempts(ctx, filmId, table)
+	if err != nil || len(attempts) > 0 {
+		return attempts, err
+	}
+	for _, t := range opTypes {
+		exists, err := p.dbService.FilmExists(ctx, t.String(), filmId)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return attempts, nil
+		}
+	}
+	return nil, ErrFilmNotFound
+}
+
+func (p *Processor) finishJob(ctx context.Context, job *models.Job, err error) {
+	finishedAt := time.Now().UTC()
+	job.FinishedAt = &finishedAt
+	job.Status = models.JOB_COMPLETED
+	if err != nil {
+		job.Status = models.JOB_FAILED
+		job.Error = err.Error()
+	}
+	p.dbService.UpdateJob(ctx, job)
+	stats := p.metadata.Stats()
+	p.logger.Info().Msgf("job %d for %s finished with status: %s, metadata cache hit rate: %.2f", job.Id, job.OperationType, job.Status, stats.HitRate)
+}
 
-			if resOk && qOk && gOk {
-				p.logger.Info().Msgf("perfect matched %s", torrent.Title)
-				return true
+// searchTorrents searches the profile resolutions in preference order until a resolution has
+// candidates accepted by the profile and verified against the film.
+func (p *Processor) searchTorrents(ctx context.Context, profile *models.QualityProfile, film models.FilmItem, provider string, title string) ([]models.Torrent, error) {
+	resolutions := profile.Resolutions
+	if len(resolutions) == 0 {
+		// any resolution
+		resolutions = []string{""}
+	}
+	for _, resolution := range resolutions {
+		torrents, err := p.apiService.FetchTorrents(ctx, models.FilterParams{Provider: provider, Term: title, Resolution: resolution})
+		if err != nil {
+			return nil, err
+		}
+		var accepted []models.Torrent
+		for _, torrent := range torrents {
+			p.enrichTorrent(&torrent)
+			if ok, reason := acceptTorrent(profile, torrent); !ok {
+				p.logger.Info().Msgf("torrent %s rejected by %s profile: %s", torrent.Title, profile.Name, reason)
+				continue
 			}
-			// partial match either a group, title or quality matches
-			if (resOk && qOk) || (resOk && gOk) || (qOk && gOk) {
-				p.logger.Warn().Msgf("partial match for %s, %t, %t, %t", torrent.Title, resOk, qOk, gOk)
-				partialMatch = true
-				torr = &torrent
-				return true
+			if ok, reason := p.verifyTorrent(film, torrent); !ok {
+				p.logger.Info().Msgf("torrent %s rejected for film id %d: %s", torrent.Title, film.Id, reason)
+				continue
 			}
-			return false
-		})
-		if partialMatch {
-			return torr
+			accepted = append(accepted, torrent)
 		}
-		if ok && !partialMatch {
-			return &torrent
+		if len(accepted) > 0 {
+			return accepted, nil
 		}
+		p.logger.Info().Msgf("no %s candidates for %s, falling back to next resolution", resolution, title)
 	}
-	return nil
+	return nil, nil
 }
 
-func (p *Processor) hasFileSubtitles(torrents []models.Torrent) (*models.Torrent, bool, bool) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	for _, torrent := range torrents {
-		metadata, err := p.apiService.GetTorrentMetadata(ctx, &torrent)
+// getSubtitles requests the online subtitles of every wanted language, subtitles are tagged with
+// their language.
+func (p *Processor) getSubtitles(ctx context.Context, title string, wanted []models.SubtitleLanguage) ([]models.Subtitle, error) {
+	var subs []models.Subtitle
+	for _, language := range wanted {
+		items, err := p.apiService.GetSubtitles(ctx, title, language.Code)
 		if err != nil {
-			p.logger.Err(err).Msgf("error while receiving metadata for torrent: %s", torrent.Title)
-			return nil, false, false
+			return subs, err
 		}
+		for _, item := range items {
+			enrichSubtitle(&item)
+			item.Language = subtitles.Tag(language, item)
+			subs = append(subs, item)
+		}
+	}
+	return subs, nil
+}
 
-		p.logger.Info().Msgf("%d total files found in torrent's metadata: %s", len(metadata.Data.Files), torrent.Title)
-
-		for _, file := range metadata.Data.Files {
-			extension := path.Ext(file.Name)
-			if extension == ".srt" || extension == ".ass" {
-				if strings.Contains("spa", file.Name) || strings.Contains("latin", file.Name) {
-					return &torrent, true, true
-				}
-				return &torrent, true, false
+// matchSubtitles returns the online subtitle matching best the torrent for every wanted language
+// missing from the torrent files, with their mean match score. Pairs scoring below
+// config.SubtitleMatchThreshold are not matched.
+func (p *Processor) matchSubtitles(torrent models.Torrent, subs []models.Subtitle, wanted []models.SubtitleLanguage, embedded subtitles.Languages) ([]models.Subtitle, float64) {
+	var matched []models.Subtitle
+	var total float64
+	for _, language := range wanted {
+		if embedded.Has(language.Code) {
+			continue
+		}
+		var candidates []models.Subtitle
+		for _, s := range subs {
+			if (subtitles.Languages{s.Language: true}).Has(language.Code) {
+				candidates = append(candidates, s)
 			}
 		}
+		pair, ok := p.matcher.Match(&torrent, candidates)
+		if !ok || pair.Score < p.config.SubtitleMatchThreshold {
+			continue
+		}
+		p.logger.Info().Msgf("%s subtitle %s matched %s with score %.2f %v", language.Code, pair.Subtitle.Title, torrent.Title, pair.Score, pair.Breakdown)
+		matched = append(matched, *pair.Subtitle)
+		total += pair.Score
+	}
+	if len(matched) == 0 {
+		return nil, 0
 	}
-	return nil, false, false
+	return matched, total / float64(len(matched))
 }
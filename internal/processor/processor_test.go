@@ -0,0 +1,475 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/processor-films/internal/cache"
+	"github.com/xochilpili/processor-films/internal/config"
+	"github.com/xochilpili/processor-films/internal/database"
+	"github.com/xochilpili/processor-films/internal/groups"
+	"github.com/xochilpili/processor-films/internal/matcher"
+	"github.com/xochilpili/processor-films/internal/models"
+	"github.com/xochilpili/processor-films/internal/ranking"
+	"github.com/xochilpili/processor-films/internal/services"
+	"github.com/xochilpili/processor-films/internal/subtitles"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		BatchSize:              10,
+		Workers:                1,
+		FestivalsProfile:       "default",
+		PopularProfile:         "default",
+		QualityProfiles:        config.QualityProfiles{"default": {Name: "default"}},
+		SubtitleLanguages:      config.SubtitleLanguages{{Code: "es"}},
+		TitleSimilarity:        0.85,
+		YearTolerance:          1,
+		SubtitleMatchThreshold: 0.6,
+	}
+}
+
+// newTestProcessor returns a processor with fake services, the fakes are reachable through
+// p.dbService.(*fakeDatabase), p.apiService.(*fakeApi) and p.downloader.(*fakeDownloader).
+func newTestProcessor(cfg *config.Config) *Processor {
+	logger := zerolog.Nop()
+	db := newFakeDatabase()
+	aliases := groups.New(cfg.GroupAliases)
+	return &Processor{
+		config:     cfg,
+		logger:     &logger,
+		dbService:  db,
+		apiService: &fakeApi{},
+		downloader: &fakeDownloader{},
+		ranker:     ranking.New(cfg, &logger, aliases),
+		metadata:   cache.New(cfg, &logger, db),
+		groups:     aliases,
+		matcher:    matcher.New(aliases),
+		locks: map[models.OperationType]*sync.Mutex{
+			models.FESTIVALS: {},
+			models.POPULAR:   {},
+		},
+	}
+}
+
+// fakeDatabase keeps films and jobs in memory, embedded methods not overridden panic.
+type fakeDatabase struct {
+	DatabaseService
+	mu          sync.Mutex
+	films       []models.FilmItem
+	limits      []int
+	jobs        int
+	jobFilms    []models.JobFilm
+	attempted   []int
+	processed   []int
+	interrupted []string
+	aliases     []models.GroupAlias
+	pending     map[string][]models.FilmDownload
+	saved       []models.FilmDownload
+	awaiting    []models.FilmDownload
+	maxAttempts int
+	placed      []models.DownloadSubtitle
+	// held are the advisory locks taken by another replica
+	held     map[string]bool
+	locks    map[string]*sync.Mutex
+	finished chan models.Job
+}
+
+func newFakeDatabase() *fakeDatabase {
+	return &fakeDatabase{held: map[string]bool{}, locks: map[string]*sync.Mutex{}, finished: make(chan models.Job, 10)}
+}
+
+func (d *fakeDatabase) Connect() error { return nil }
+
+func (d *fakeDatabase) CreateJob(ctx context.Context, job *models.Job) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.jobs++
+	job.Id = d.jobs
+	job.StartedAt = time.Now()
+	return nil
+}
+
+func (d *fakeDatabase) UpdateJob(ctx context.Context, job *models.Job) error {
+	if job.FinishedAt != nil {
+		d.finished <- *job
+	}
+	return nil
+}
+
+func (d *fakeDatabase) InterruptJobs(ctx context.Context, operationType string, reason string) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.interrupted = append(d.interrupted, operationType)
+	return 0, nil
+}
+
+func (d *fakeDatabase) AcquireLock(ctx context.Context, name string, wait bool) (func(), error) {
+	d.mu.Lock()
+	if d.held[name] {
+		d.mu.Unlock()
+		return nil, database.ErrLockNotAcquired
+	}
+	mu, ok := d.locks[name]
+	if !ok {
+		mu = &sync.Mutex{}
+		d.locks[name] = mu
+	}
+	d.mu.Unlock()
+	if wait {
+		mu.Lock()
+	} else if !mu.TryLock() {
+		return nil, database.ErrLockNotAcquired
+	}
+	return mu.Unlock, nil
+}
+
+func (d *fakeDatabase) GetOlderFilms(ctx context.Context, table string, afterId int, limit int) ([]models.FilmItem, error) {
+	return nil, nil
+}
+
+func (d *fakeDatabase) GetFilms(ctx context.Context, table string, columns []string, provider string, afterId int, limit int) ([]models.FilmItem, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.limits = append(d.limits, limit)
+	var films []models.FilmItem
+	for _, film := range d.films {
+		if film.Id > afterId && len(films) < limit {
+			films = append(films, film)
+		}
+	}
+	return films, nil
+}
+
+func (d *fakeDatabase) GetGroupAliases(ctx context.Context) ([]models.GroupAlias, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.aliases, nil
+}
+
+func (d *fakeDatabase) SaveGroupAlias(ctx context.Context, alias models.GroupAlias) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.aliases = append(d.aliases, alias)
+	return nil
+}
+
+func (d *fakeDatabase) AddJobFilm(ctx context.Context, jobId int, film *models.JobFilm) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.jobFilms = append(d.jobFilms, *film)
+	return nil
+}
+
+func (d *fakeDatabase) AddFilmAttempt(ctx context.Context, attempt *models.FilmProcessAttempt) error {
+	return nil
+}
+
+func (d *fakeDatabase) UpdateProcess(ctx context.Context, table string, id int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.attempted = append(d.attempted, id)
+	return nil
+}
+
+func (d *fakeDatabase) ProcessedFilm(ctx context.Context, table string, id int, hash string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.processed = append(d.processed, id)
+	return nil
+}
+
+func (d *fakeDatabase) GetPendingDownloads(ctx context.Context, table string) ([]models.FilmDownload, error) {
+	return d.pending[table], nil
+}
+
+func (d *fakeDatabase) SaveDownload(ctx context.Context, download *models.FilmDownload) error {
+	d.saved = append(d.saved, *download)
+	return nil
+}
+
+func (d *fakeDatabase) GetDownloadsAwaitingSubtitles(ctx context.Context, maxAttempts int) ([]models.FilmDownload, error) {
+	d.maxAttempts = maxAttempts
+	return d.awaiting, nil
+}
+
+func (d *fakeDatabase) UpdateDownloadSubtitle(ctx context.Context, download *models.FilmDownload, subtitle *models.DownloadSubtitle) error {
+	d.placed = append(d.placed, *subtitle)
+	return nil
+}
+
+func (d *fakeDatabase) SetDownloadSubtitles(ctx context.Context, download *models.FilmDownload) error {
+	return nil
+}
+
+// fakeApi answers with the configured funcs, torrents are not found when they are nil.
+type fakeApi struct {
+	ApiService
+	fetchTorrents func(ctx context.Context, params models.FilterParams) ([]models.Torrent, error)
+	metadata      func(ctx context.Context, torrent *models.Torrent) (*models.TorrentMetadata, error)
+	subtitles     func(ctx context.Context, title string, language string) ([]models.Subtitle, error)
+	download      func(ctx context.Context, id int) ([]byte, string, error)
+}
+
+func (a *fakeApi) FetchTorrents(ctx context.Context, params models.FilterParams) ([]models.Torrent, error) {
+	if a.fetchTorrents == nil {
+		return nil, nil
+	}
+	return a.fetchTorrents(ctx, params)
+}
+
+func (a *fakeApi) GetTorrentMetadata(ctx context.Context, torrent *models.Torrent) (*models.TorrentMetadata, error) {
+	return a.metadata(ctx, torrent)
+}
+
+func (a *fakeApi) GetSubtitles(ctx context.Context, title string, language string) ([]models.Subtitle, error) {
+	if a.subtitles == nil {
+		return nil, nil
+	}
+	return a.subtitles(ctx, title, language)
+}
+
+func (a *fakeApi) DownloadSubtitle(ctx context.Context, id int) ([]byte, string, error) {
+	return a.download(ctx, id)
+}
+
+func (a *fakeApi) ResetBreakers() {}
+
+type fakeDownloader struct {
+	DownloadClient
+	addTorrent func(ctx context.Context, magnetLink string, opts models.AddTorrentOptions) (string, error)
+	torrents   []models.BitTorrent
+}
+
+func (d *fakeDownloader) AddTorrent(ctx context.Context, magnetLink string, opts models.AddTorrentOptions) (string, error) {
+	return d.addTorrent(ctx, magnetLink, opts)
+}
+
+func (d *fakeDownloader) Torrents(ctx context.Context, hashes []string) ([]models.BitTorrent, error) {
+	return d.torrents, nil
+}
+
+func (d *fakeDownloader) ResetBreakers() {}
+
+func TestMatchSubtitlesPerLanguage(t *testing.T) {
+	wanted := []models.SubtitleLanguage{{Code: "es"}, {Code: "en"}}
+	cfg := testConfig()
+	cfg.SubtitleLanguages = wanted
+	p := newTestProcessor(cfg)
+	torrent := models.Torrent{
+		Title:      "Movie.2024.1080p.WEBRip.x264-RARBG",
+		Group:      "RARBG",
+		Quality:    "WEBRip",
+		Resolution: "1080p",
+	}
+	subs := []models.Subtitle{
+		{Id: 1, Title: "Movie.2024.720p.BluRay.x264-SPARKS", Group: []string{"SPARKS"}, Quality: []string{"BluRay"}, Resolution: []string{"720p"}, Language: "es"},
+		{Id: 2, Title: "Movie.2024.1080p.WEBRip.x264-RARBG", Group: []string{"RARBG"}, Quality: []string{"WEBRip"}, Resolution: []string{"1080p"}, Language: "es-419"},
+		{Id: 3, Title: "Movie.2024.1080p.WEBRip.x264-RARBG", Group: []string{"RARBG"}, Quality: []string{"WEBRip"}, Resolution: []string{"1080p"}, Language: "en"},
+	}
+
+	matched, score := p.matchSubtitles(torrent, subs, wanted, subtitles.Languages{})
+	if len(matched) != 2 || matched[0].Id != 2 || matched[1].Id != 3 {
+		t.Fatalf("matched %+v, want subtitles 2 and 3", matched)
+	}
+	if score < 0.6 {
+		t.Errorf("match score %.2f below threshold", score)
+	}
+	c := ranking.Candidate{Torrent: torrent, Wanted: wanted, Languages: subtitles.Languages{}, Subtitles: matched, SubtitleMatch: score}
+	if !c.Satisfied() {
+		t.Errorf("candidate with es and en subtitles is not satisfied")
+	}
+
+	// embedded languages are not matched again
+	matched, _ = p.matchSubtitles(torrent, subs, wanted, subtitles.Languages{"en": true})
+	if len(matched) != 1 || matched[0].Id != 2 {
+		t.Errorf("matched %+v with embedded en, want subtitle 2", matched)
+	}
+}
+
+func TestRunReturnsJobCopy(t *testing.T) {
+	p := newTestProcessor(testConfig())
+	db := p.dbService.(*fakeDatabase)
+
+	job, err := p.Run(context.Background(), models.FESTIVALS, models.RunOptions{})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	// serialized while the worker finishes the job, go test -race reports shared jobs
+	if _, err := json.Marshal(job); err != nil {
+		t.Fatal(err)
+	}
+	finished := <-db.finished
+	if finished.Status != models.JOB_COMPLETED {
+		t.Errorf("job status = %s, want %s", finished.Status, models.JOB_COMPLETED)
+	}
+	if job.Status != models.JOB_RUNNING || job.FinishedAt != nil {
+		t.Errorf("returned job = %+v, want the running job", job)
+	}
+}
+
+func TestConnectInterruptsStaleJobs(t *testing.T) {
+	p := newTestProcessor(testConfig())
+	db := p.dbService.(*fakeDatabase)
+	// popular is running in another replica
+	db.held[models.POPULAR.String()] = true
+
+	if err := p.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if len(db.interrupted) != 1 || db.interrupted[0] != models.FESTIVALS.String() {
+		t.Errorf("interrupted = %v, want only %s", db.interrupted, models.FESTIVALS.String())
+	}
+}
+
+func TestRunInProgress(t *testing.T) {
+	p := newTestProcessor(testConfig())
+	db := p.dbService.(*fakeDatabase)
+	db.films = []models.FilmItem{{Id: 1, Title: "Alien", Year: 1979}}
+	started := make(chan struct{})
+	block := make(chan struct{})
+	var once sync.Once
+	p.apiService.(*fakeApi).fetchTorrents = func(ctx context.Context, params models.FilterParams) ([]models.Torrent, error) {
+		once.Do(func() { close(started) })
+		<-block
+		return nil, nil
+	}
+
+	if _, err := p.Run(context.Background(), models.FESTIVALS, models.RunOptions{}); err != nil {
+		t.Fatalf("first Run() error = %v", err)
+	}
+	<-started
+
+	if _, err := p.Run(context.Background(), models.FESTIVALS, models.RunOptions{}); !errors.Is(err, ErrRunInProgress) {
+		t.Errorf("concurrent Run() error = %v, want %v", err, ErrRunInProgress)
+	}
+	queued, err := p.Run(context.Background(), models.FESTIVALS, models.RunOptions{Wait: true})
+	if err != nil {
+		t.Fatalf("waiting Run() error = %v", err)
+	}
+	if queued.Status != models.JOB_QUEUED {
+		t.Errorf("waiting Run() status = %s, want %s", queued.Status, models.JOB_QUEUED)
+	}
+
+	close(block)
+	for i := 0; i < 2; i++ {
+		if job := <-db.finished; job.Status != models.JOB_COMPLETED {
+			t.Errorf("job %d status = %s, want %s", job.Id, job.Status, models.JOB_COMPLETED)
+		}
+	}
+}
+
+func TestRunInProgressInAnotherReplica(t *testing.T) {
+	p := newTestProcessor(testConfig())
+	p.dbService.(*fakeDatabase).held[models.POPULAR.String()] = true
+
+	if _, err := p.Run(context.Background(), models.POPULAR, models.RunOptions{}); !errors.Is(err, ErrRunInProgress) {
+		t.Errorf("Run() error = %v, want %v", err, ErrRunInProgress)
+	}
+	if _, err := p.Run(context.Background(), models.FESTIVALS, models.RunOptions{}); err != nil {
+		t.Errorf("Run() of another operation type error = %v", err)
+	}
+}
+
+func TestProcessStopsAtMaxFilms(t *testing.T) {
+	p := newTestProcessor(testConfig())
+	db := p.dbService.(*fakeDatabase)
+	for id := 1; id <= 7; id++ {
+		db.films = append(db.films, models.FilmItem{Id: id, Title: fmt.Sprintf("Film %d", id), Year: 2020})
+	}
+
+	job := &models.Job{Id: 1, Provider: "all", BatchSize: 3, MaxFilms: 5, Profile: &models.QualityProfile{Name: "default"}}
+	if err := p.Process(context.Background(), job, models.FESTIVALS); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if want := []int{3, 2}; !reflect.DeepEqual(db.limits, want) {
+		t.Errorf("batch limits = %v, want %v", db.limits, want)
+	}
+	var ids []int
+	for _, film := range db.jobFilms {
+		ids = append(ids, film.FilmId)
+	}
+	sort.Ints(ids)
+	if want := []int{1, 2, 3, 4, 5}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("processed films = %v, want %v", ids, want)
+	}
+	if len(job.Films) != 5 {
+		t.Errorf("job films = %d, want 5", len(job.Films))
+	}
+}
+
+func TestProcessRecoversFilmPanic(t *testing.T) {
+	p := newTestProcessor(testConfig())
+	db := p.dbService.(*fakeDatabase)
+	db.films = []models.FilmItem{{Id: 1, Title: "Alien"}, {Id: 2, Title: "Aliens"}, {Id: 3, Title: "Alien 3"}}
+	p.apiService.(*fakeApi).fetchTorrents = func(ctx context.Context, params models.FilterParams) ([]models.Torrent, error) {
+		if params.Term == "Aliens" {
+			panic("boom")
+		}
+		return nil, nil
+	}
+
+	job := &models.Job{Id: 1, Provider: "all", BatchSize: 10, Profile: &models.QualityProfile{Name: "default"}}
+	if err := p.Process(context.Background(), job, models.POPULAR); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if len(db.jobFilms) != 3 {
+		t.Fatalf("job films = %d, want 3", len(db.jobFilms))
+	}
+	for _, film := range db.jobFilms {
+		failed := film.Outcome == models.OUTCOME_FAILED && strings.Contains(film.Error, "boom")
+		if failed != (film.FilmId == 2) {
+			t.Errorf("film %d outcome = %s, error = %q", film.FilmId, film.Outcome, film.Error)
+		}
+	}
+}
+
+func TestAddTorrent(t *testing.T) {
+	rejected := &services.UpstreamError{Kind: services.ErrBadResponse, Upstream: services.DOWNLOAD_CLIENT}
+	tests := []struct {
+		name      string
+		hash      string
+		err       error
+		processed bool
+		outcome   models.FilmOutcome
+	}{
+		{"added", "c12fe1c06bba254a9dc9f519b335aa7c1367a88a", nil, true, models.OUTCOME_ADDED},
+		{"duplicate", "c12fe1c06bba254a9dc9f519b335aa7c1367a88a", services.ErrDuplicateTorrent, true, models.OUTCOME_ADDED},
+		{"rejected", "", rejected, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProcessor(testConfig())
+			db := p.dbService.(*fakeDatabase)
+			p.downloader.(*fakeDownloader).addTorrent = func(ctx context.Context, magnetLink string, opts models.AddTorrentOptions) (string, error) {
+				return tt.hash, tt.err
+			}
+			film := models.FilmItem{Id: 1, Title: "Alien", Year: 1979}
+			torrent := &models.Torrent{Title: "Alien.1979.1080p.BluRay.x264-YTS", Magnet: "magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a"}
+			result := &models.JobFilm{FilmId: film.Id}
+
+			err := p.addTorrent(context.Background(), models.POPULAR, film, torrent, models.SUBTITLE_EMBEDDED, nil, result)
+			if tt.processed && err != nil {
+				t.Fatalf("addTorrent() error = %v", err)
+			}
+			if !tt.processed && !errors.Is(err, services.ErrBadResponse) {
+				t.Fatalf("addTorrent() error = %v, want the download client error", err)
+			}
+			if processed := len(db.processed) > 0; processed != tt.processed {
+				t.Errorf("film processed = %v, want %v", processed, tt.processed)
+			}
+			if result.Outcome != tt.outcome {
+				t.Errorf("outcome = %q, want %q", result.Outcome, tt.outcome)
+			}
+		})
+	}
+}
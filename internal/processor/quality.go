@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/xochilpili/processor-films/internal/models"
+	"github.com/xochilpili/processor-films/internal/ranking"
+)
+
+var ErrUnknownProfile = errors.New("unknown quality profile")
+
+// profile returns the quality profile of the run, opts overrides the configured profile of the operation type.
+func (p *Processor) profile(opType models.OperationType, opts models.RunOptions) (*models.QualityProfile, error) {
+	name := opts.Profile
+	if name == "" {
+		switch opType {
+		case models.FESTIVALS:
+			name = p.config.FestivalsProfile
+		case models.POPULAR:
+			name = p.config.PopularProfile
+		}
+	}
+	profile, ok := p.config.QualityProfiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProfile, name)
+	}
+	if len(opts.Resolutions) > 0 {
+		profile.Resolutions = opts.Resolutions
+	}
+	return &profile, nil
+}
+
+// acceptTorrent checks the torrent against the profile restrictions, it returns the rejection reason otherwise.
+func acceptTorrent(profile *models.QualityProfile, torrent models.Torrent) (bool, string) {
+	if len(profile.Codecs) > 0 && torrent.Codec != "" && !containsFold(profile.Codecs, torrent.Codec) {
+		return false, fmt.Sprintf("codec %s not allowed", torrent.Codec)
+	}
+	if size, ok := ranking.ParseSize(torrent.Size); ok {
+		if profile.MinSize > 0 && size < profile.MinSize {
+			return false, fmt.Sprintf("size %s below minimum", torrent.Size)
+		}
+		if profile.MaxSize > 0 && size > profile.MaxSize {
+			return false, fmt.Sprintf("size %s above maximum", torrent.Size)
+		}
+	}
+	// keywords are compared by token so "ts" does not ban every title containing those letters
+	tokens := strings.FieldsFunc(strings.ToLower(torrent.Title+" "+torrent.Quality), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, keyword := range profile.BannedKeywords {
+		if containsFold(tokens, keyword) {
+			return false, fmt.Sprintf("banned keyword %s", keyword)
+		}
+	}
+	return true, ""
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
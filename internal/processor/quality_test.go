@@ -0,0 +1,101 @@
+package processor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+func TestAcceptTorrent(t *testing.T) {
+	profile := &models.QualityProfile{
+		Name:           "strict",
+		Codecs:         []string{"x264", "x265"},
+		MinSize:        700 << 20,
+		MaxSize:        4 << 30,
+		BannedKeywords: []string{"cam", "ts", "hdcam"},
+	}
+	tests := []struct {
+		name    string
+		torrent models.Torrent
+		want    bool
+	}{
+		{"accepted", models.Torrent{Title: "Alien.1979.1080p.BluRay.x264-YTS", Codec: "x264", Size: "1.8 GB"}, true},
+		{"codec case", models.Torrent{Title: "Alien.1979.1080p.BluRay.X265-RARBG", Codec: "X265", Size: "1.8 GB"}, true},
+		{"unknown codec", models.Torrent{Title: "Alien.1979.1080p.BluRay-YTS", Size: "1.8 GB"}, true},
+		{"unknown size", models.Torrent{Title: "Alien.1979.1080p.BluRay.x264-YTS", Codec: "x264"}, true},
+		{"codec", models.Torrent{Title: "Alien.1979.1080p.BluRay.XviD-YTS", Codec: "xvid", Size: "1.8 GB"}, false},
+		{"below minimum", models.Torrent{Title: "Alien.1979.720p.BluRay.x264-YTS", Codec: "x264", Size: "350 MB"}, false},
+		{"above maximum", models.Torrent{Title: "Alien.1979.1080p.BluRay.REMUX.x264-FGT", Codec: "x264", Size: "28.4 GB"}, false},
+		{"minimum bound", models.Torrent{Title: "Alien.1979.720p.BluRay.x264-YTS", Codec: "x264", Size: "700 MB"}, true},
+		{"maximum bound", models.Torrent{Title: "Alien.1979.1080p.BluRay.x264-YTS", Codec: "x264", Size: "4 GB"}, true},
+		{"banned keyword", models.Torrent{Title: "Alien Romulus 2024 HDCAM x264", Codec: "x264", Size: "1.8 GB"}, false},
+		{"banned quality", models.Torrent{Title: "Alien.Romulus.2024.x264", Quality: "TS", Codec: "x264", Size: "1.8 GB"}, false},
+		{"keyword inside a word", models.Torrent{Title: "Bats.1999.1080p.BluRay.x264-CAMELOT", Codec: "x264", Size: "1.8 GB"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ok, reason := acceptTorrent(profile, tt.torrent); ok != tt.want {
+				t.Errorf("acceptTorrent(%s) = %v (%s), want %v", tt.torrent.Title, ok, reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchTorrentsResolutionFallback(t *testing.T) {
+	p := newTestProcessor(testConfig())
+	profile := &models.QualityProfile{Name: "hd", Resolutions: []string{"2160p", "1080p", "720p"}, BannedKeywords: []string{"cam"}}
+	results := map[string][]models.Torrent{
+		// rejected by the profile and by the film verification
+		"2160p": {
+			{Title: "Alien.1979.2160p.CAM.x265-NOPE", Resolution: "2160p"},
+			{Title: "Aliens.1986.2160p.BluRay.x265-TERMiNAL", Resolution: "2160p"},
+		},
+		"1080p": {
+			{Title: "Alien.1979.1080p.BluRay.x264-YTS", Resolution: "1080p"},
+			{Title: "Alien.1979.Directors.Cut.1080p.BluRay.x265-RARBG", Resolution: "1080p"},
+		},
+		"720p": {{Title: "Alien.1979.720p.BluRay.x264-YTS", Resolution: "720p"}},
+	}
+	var searched []string
+	p.apiService.(*fakeApi).fetchTorrents = func(ctx context.Context, params models.FilterParams) ([]models.Torrent, error) {
+		searched = append(searched, params.Resolution)
+		return results[params.Resolution], nil
+	}
+
+	film := models.FilmItem{Id: 1, Title: "Alien", Year: 1979}
+	torrents, err := p.searchTorrents(context.Background(), profile, film, "all", "Alien")
+	if err != nil {
+		t.Fatalf("searchTorrents() error = %v", err)
+	}
+	if want := []string{"2160p", "1080p"}; !reflect.DeepEqual(searched, want) {
+		t.Errorf("searched resolutions = %v, want %v", searched, want)
+	}
+	var titles []string
+	for _, torrent := range torrents {
+		titles = append(titles, torrent.Title)
+	}
+	if want := []string{"Alien.1979.1080p.BluRay.x264-YTS", "Alien.1979.Directors.Cut.1080p.BluRay.x265-RARBG"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("searchTorrents() = %v, want %v", titles, want)
+	}
+}
+
+func TestSearchTorrentsNotFound(t *testing.T) {
+	p := newTestProcessor(testConfig())
+	var searched []string
+	p.apiService.(*fakeApi).fetchTorrents = func(ctx context.Context, params models.FilterParams) ([]models.Torrent, error) {
+		searched = append(searched, params.Resolution)
+		return nil, nil
+	}
+
+	profile := &models.QualityProfile{Name: "any"}
+	torrents, err := p.searchTorrents(context.Background(), profile, models.FilmItem{Id: 1, Title: "Alien", Year: 1979}, "all", "Alien")
+	if err != nil || len(torrents) != 0 {
+		t.Fatalf("searchTorrents() = %v, %v", torrents, err)
+	}
+	// profiles without resolutions search any resolution once
+	if want := []string{""}; !reflect.DeepEqual(searched, want) {
+		t.Errorf("searched resolutions = %v, want %v", searched, want)
+	}
+}
@@ -0,0 +1,83 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xochilpili/processor-films/internal/models"
+	"github.com/xochilpili/processor-films/internal/release"
+)
+
+// enrichTorrent fills the fields torrent-api left blank from the parsed release name and logs
+// the fields which disagree with it.
+func (p *Processor) enrichTorrent(torrent *models.Torrent) {
+	r := release.Parse(torrent.Title)
+	fill := func(field string, value *string, parsed string) {
+		switch {
+		case parsed == "":
+		case *value == "":
+			*value = parsed
+		case !strings.EqualFold(*value, parsed):
+			p.logger.Debug().Msgf("torrent %s %s is %s but its name says %s", torrent.Title, field, *value, parsed)
+		}
+	}
+	fill("resolution", &torrent.Resolution, r.Resolution)
+	fill("quality", &torrent.Quality, r.Source)
+	fill("codec", &torrent.Codec, r.Codec)
+	fill("group", &torrent.Group, r.Group)
+}
+
+// enrichSubtitle adds the resolutions, qualities and groups of the release names found in the
+// subtitle title and description, where uploaders list the releases the subtitle syncs with.
+func enrichSubtitle(subtitle *models.Subtitle) {
+	names := []string{subtitle.Title}
+	for _, field := range strings.FieldsFunc(subtitle.Description, func(r rune) bool {
+		return r == ' ' || r == ',' || r == ';' || r == '/' || r == '\n' || r == '\t'
+	}) {
+		// release names are dotted or hyphenated, e.g. Movie.2023.1080p.WEB-DL-GROUP
+		if len(field) > 8 && strings.ContainsAny(field, ".-") {
+			names = append(names, field)
+		}
+	}
+	for _, name := range names {
+		r := release.Parse(name)
+		subtitle.Resolution = appendMissing(subtitle.Resolution, r.Resolution)
+		subtitle.Quality = appendMissing(subtitle.Quality, r.Source)
+		subtitle.Group = appendMissing(subtitle.Group, r.Group)
+	}
+}
+
+func appendMissing(values []string, value string) []string {
+	if value == "" || containsFold(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+// verifyTorrent compares the title and year parsed from the torrent name, and its original title,
+// with the film, it returns the rejection reason otherwise. Unknown years are not compared.
+func (p *Processor) verifyTorrent(film models.FilmItem, torrent models.Torrent) (bool, string) {
+	r := release.Parse(torrent.Title)
+	similarity := release.TitleSimilarity(film.Title, r.Title)
+	if torrent.OriginalTitle != "" {
+		similarity = max(similarity, release.TitleSimilarity(film.Title, torrent.OriginalTitle))
+	}
+	if similarity < p.config.TitleSimilarity {
+		return false, fmt.Sprintf("title %q does not match %q (similarity %.2f)", r.Title, film.Title, similarity)
+	}
+	year := r.Year
+	if year == 0 {
+		year = torrent.Year
+	}
+	if film.Year > 0 && year > 0 && abs(film.Year-year) > p.config.YearTolerance {
+		return false, fmt.Sprintf("year %d does not match %d", year, film.Year)
+	}
+	return true, ""
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
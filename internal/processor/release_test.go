@@ -0,0 +1,42 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+func TestVerifyTorrent(t *testing.T) {
+	p := newTestProcessor(testConfig())
+	tests := []struct {
+		film    models.FilmItem
+		torrent string
+		want    bool
+	}{
+		{models.FilmItem{Title: "The Italian Job", Year: 2003}, "The.Italian.Job.2003.1080p.BluRay.x264-YTS", true},
+		{models.FilmItem{Title: "The Italian Job", Year: 2003}, "The Italian Job (2003) [720p] [BluRay] [YTS.MX]", true},
+		{models.FilmItem{Title: "The Italian Job", Year: 2003}, "The.Italian.Job.1969.1080p.BluRay.x264-AMIABLE", false},
+		{models.FilmItem{Title: "The English Patient", Year: 1996}, "The.English.Patient.1996.1080p.BluRay.x264-USURY", true},
+		{models.FilmItem{Title: "The French Dispatch", Year: 2021}, "The.French.Dispatch.2021.1080p.WEBRip.x265-RARBG", true},
+		{models.FilmItem{Title: "The Spanish Prisoner", Year: 1997}, "The.Spanish.Prisoner.1997.1080p.WEB-DL.DD5.1.H264-FGT", true},
+		{models.FilmItem{Title: "Charlotte's Web", Year: 2006}, "Charlottes.Web.2006.1080p.BluRay.x264-CiNEFiLE", true},
+		{models.FilmItem{Title: "Charlotte's Web", Year: 2006}, "Charlotte's Web (2006) [1080p] [WEBRip] [5.1] [YTS.MX]", true},
+		{models.FilmItem{Title: "Blade Runner 2049", Year: 2017}, "Blade.Runner.2049.2017.2160p.UHD.BluRay.x265-TERMiNAL", true},
+		{models.FilmItem{Title: "1917", Year: 2019}, "1917.2019.1080p.BluRay.x264-SPARKS", true},
+		{models.FilmItem{Title: "Alien", Year: 1979}, "Alien.3.1992.Special.Edition.1080p.BluRay.x264-AMIABLE", false},
+		{models.FilmItem{Title: "Rocky IV", Year: 1985}, "Rocky.4.1985.REMASTERED.1080p.BluRay.x264-GUACAMOLE", true},
+		{models.FilmItem{Title: "Rocky V", Year: 1990}, "Rocky.V.1990.1080p.BluRay.x264-AMIABLE", true},
+		{models.FilmItem{Title: "Rocky V", Year: 1990}, "Rocky.IV.1985.1080p.BluRay.x264-AMIABLE", false},
+		{models.FilmItem{Title: "Malcolm X", Year: 1992}, "Malcolm.X.1992.1080p.BluRay.x264-AMIABLE", true},
+		{models.FilmItem{Title: "V for Vendetta", Year: 2005}, "V.for.Vendetta.2005.1080p.BluRay.x264-HDMaNiAcS", true},
+		{models.FilmItem{Title: "V for Vendetta", Year: 2005}, "V For Vendetta (2005) [1080p] [BluRay] [YTS.MX]", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.torrent, func(t *testing.T) {
+			ok, reason := p.verifyTorrent(tt.film, models.Torrent{Title: tt.torrent})
+			if ok != tt.want {
+				t.Errorf("verifyTorrent(%s, %s) = %v %s, want %v", tt.film.Title, tt.torrent, ok, reason, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xochilpili/processor-films/internal/models"
+	"github.com/xochilpili/processor-films/internal/subtitles"
+)
+
+// trackingBatchSize is the number of hashes requested at once to the download client.
+const trackingBatchSize = 50
+
+// TrackDownloads refreshes the download state of every film added to the download client
+// which has not finished downloading yet.
+func (p *Processor) TrackDownloads(ctx context.Context) error {
+	for _, opType := range models.OperationTypes {
+		pending, err := p.dbService.GetPendingDownloads(ctx, opType.String())
+		if err != nil {
+			p.logger.Err(err).Msgf("error while getting pending %s downloads", opType.String())
+			return fmt.Errorf("error while getting pending downloads: %w", err)
+		}
+		for start := 0; start < len(pending); start += trackingBatchSize {
+			end := min(start+trackingBatchSize, len(pending))
+			if err := p.trackBatch(ctx, pending[start:end]); err != nil {
+				return err
+			}
+		}
+		p.logger.Info().Msgf("tracked %d %s downloads", len(pending), opType.String())
+	}
+	if p.config.SubtitlerDownloadUrl == "" {
+		return nil
+	}
+	return p.placeSubtitles(ctx)
+}
+
+// placeSubtitles downloads the matched online subtitles of every completed download and writes them
+// next to the video, failures are stored with the subtitle and retried on the next poll until
+// config.SubtitlePlacementAttempts is reached.
+func (p *Processor) placeSubtitles(ctx context.Context) error {
+	downloads, err := p.dbService.GetDownloadsAwaitingSubtitles(ctx, p.config.SubtitlePlacementAttempts)
+	if err != nil {
+		p.logger.Err(err).Msg("error while getting downloads awaiting subtitles")
+		return err
+	}
+	for _, download := range downloads {
+		for _, subtitle := range download.Subtitles {
+			subtitle.Path, err = p.placeSubtitle(ctx, download, subtitle)
+			subtitle.Error = ""
+			if err != nil {
+				p.logger.Err(err).Msgf("error while placing subtitle %d for %s", subtitle.SubtitleId, download.Name)
+				subtitle.Error = err.Error()
+				subtitle.Attempts++
+				if p.config.SubtitlePlacementAttempts > 0 && subtitle.Attempts >= p.config.SubtitlePlacementAttempts {
+					p.logger.Warn().Msgf("giving up subtitle %d for %s after %d attempts", subtitle.SubtitleId, download.Name, subtitle.Attempts)
+				}
+			} else {
+				p.logger.Info().Msgf("subtitle %d placed at %s", subtitle.SubtitleId, subtitle.Path)
+			}
+			if err := p.dbService.UpdateDownloadSubtitle(ctx, &download, &subtitle); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Processor) placeSubtitle(ctx context.Context, download models.FilmDownload, subtitle models.DownloadSubtitle) (string, error) {
+	data, filename, err := p.apiService.DownloadSubtitle(ctx, subtitle.SubtitleId)
+	if err != nil {
+		return "", err
+	}
+	content, ext, err := subtitles.Extract(data, filename, subtitle.Language)
+	if err != nil {
+		return "", err
+	}
+	return subtitles.Place(p.localPath(download.ContentPath), content, ext, subtitle.Language)
+}
+
+// localPath translates a download client path using config.DownloadPathMap.
+func (p *Processor) localPath(contentPath string) string {
+	from, to, ok := strings.Cut(p.config.DownloadPathMap, ":")
+	if !ok || from == "" || !strings.HasPrefix(contentPath, from) {
+		return contentPath
+	}
+	return to + strings.TrimPrefix(contentPath, from)
+}
+
+func (p *Processor) trackBatch(ctx context.Context, downloads []models.FilmDownload) error {
+	hashes := make([]string, 0, len(downloads))
+	for _, download := range downloads {
+		hashes = append(hashes, download.Hash)
+	}
+	torrents, err := p.downloader.Torrents(ctx, hashes)
+	if err != nil {
+		p.logger.Err(err).Msg("error while fetching torrents from download client")
+		return err
+	}
+	byHash := make(map[string]models.BitTorrent, len(torrents))
+	for _, torrent := range torrents {
+		byHash[torrent.Hash] = torrent
+	}
+
+	for _, download := range downloads {
+		torrent, ok := byHash[download.Hash]
+		if !ok {
+			// the last known name and paths are kept, the torrent may have been moved or removed by hand
+			download.State = models.DOWNLOAD_MISSING
+		} else {
+			applyTorrent(&download, torrent)
+		}
+		if err := p.dbService.SaveDownload(ctx, &download); err != nil {
+			return err
+		}
+		if download.CompletedAt != nil {
+			p.logger.Info().Msgf("download of %s completed for film id: %d", download.Name, download.FilmId)
+		}
+	}
+	return nil
+}
+
+func applyTorrent(download *models.FilmDownload, torrent models.BitTorrent) {
+	download.Name = torrent.Name
+	download.State = torrent.State
+	download.Progress = torrent.Progress
+	download.Ratio = torrent.Ratio
+	download.Size = torrent.TotalSize
+	download.ContentPath = torrent.ContentPath
+	if torrent.AddedOn > 0 {
+		addedAt := time.Unix(torrent.AddedOn, 0).UTC()
+		download.AddedAt = &addedAt
+	}
+	if torrent.Progress >= 1 {
+		completedAt := time.Now().UTC()
+		if torrent.CompletionOn > 0 {
+			completedAt = time.Unix(torrent.CompletionOn, 0).UTC()
+		}
+		download.CompletedAt = &completedAt
+	}
+}
+
+// GetFilmDownloads returns the downloads of the film, opType is optional.
+func (p *Processor) GetFilmDownloads(ctx context.Context, filmId int, opType *models.OperationType) ([]models.FilmDownload, error) {
+	var table string
+	if opType != nil {
+		table = opType.String()
+	}
+	return p.dbService.GetFilmDownloads(ctx, filmId, table)
+}
@@ -0,0 +1,96 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+func TestTrackDownloads(t *testing.T) {
+	p := newTestProcessor(testConfig())
+	db := p.dbService.(*fakeDatabase)
+	addedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	db.pending = map[string][]models.FilmDownload{
+		models.POPULAR.String(): {
+			{OperationType: models.POPULAR.String(), FilmId: 1, Hash: "aaa", Name: "Alien.1979", State: "downloading", Progress: 0.5},
+			{OperationType: models.POPULAR.String(), FilmId: 2, Hash: "bbb", Name: "Aliens.1986", State: "downloading", Progress: 0.2,
+				ContentPath: "/downloads/Aliens.1986", AddedAt: &addedAt},
+		},
+	}
+	p.downloader.(*fakeDownloader).torrents = []models.BitTorrent{
+		{Hash: "aaa", Name: "Alien.1979", State: "uploading", Progress: 1, TotalSize: 1024, ContentPath: "/downloads/Alien.1979", CompletionOn: 1700000000},
+	}
+
+	if err := p.TrackDownloads(context.Background()); err != nil {
+		t.Fatalf("TrackDownloads() error = %v", err)
+	}
+	if len(db.saved) != 2 {
+		t.Fatalf("saved downloads = %d, want 2", len(db.saved))
+	}
+
+	completed := db.saved[0]
+	if completed.State != "uploading" || completed.CompletedAt == nil || !completed.CompletedAt.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("completed download = %+v", completed)
+	}
+	if completed.ContentPath != "/downloads/Alien.1979" || completed.Size != 1024 {
+		t.Errorf("completed download = %+v", completed)
+	}
+
+	missing := db.saved[1]
+	if missing.State != models.DOWNLOAD_MISSING || missing.CompletedAt != nil {
+		t.Errorf("missing download state = %s, completed at = %v", missing.State, missing.CompletedAt)
+	}
+	if missing.Name != "Aliens.1986" || missing.ContentPath != "/downloads/Aliens.1986" || missing.AddedAt == nil || missing.Progress != 0.2 {
+		t.Errorf("missing download lost its last known state: %+v", missing)
+	}
+}
+
+func TestPlaceSubtitles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Alien.1979.mkv"), []byte("video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig()
+	cfg.SubtitlerDownloadUrl = "http://subtitler/download/{id}"
+	cfg.SubtitlePlacementAttempts = 3
+	p := newTestProcessor(cfg)
+	db := p.dbService.(*fakeDatabase)
+	db.awaiting = []models.FilmDownload{{
+		OperationType: models.POPULAR.String(),
+		FilmId:        1,
+		Name:          "Alien.1979",
+		ContentPath:   dir,
+		Subtitles: []models.DownloadSubtitle{
+			{SubtitleId: 10, Language: "es-419", Error: "timeout", Attempts: 1},
+			{SubtitleId: 11, Language: "en", Attempts: 2},
+		},
+	}}
+	p.apiService.(*fakeApi).download = func(ctx context.Context, id int) ([]byte, string, error) {
+		if id == 11 {
+			return nil, "", errors.New("subtitle not found")
+		}
+		return []byte("Hola"), "Alien.1979.srt", nil
+	}
+
+	if err := p.TrackDownloads(context.Background()); err != nil {
+		t.Fatalf("TrackDownloads() error = %v", err)
+	}
+	if db.maxAttempts != 3 {
+		t.Errorf("max attempts = %d, want 3", db.maxAttempts)
+	}
+	if len(db.placed) != 2 {
+		t.Fatalf("updated subtitles = %d, want 2", len(db.placed))
+	}
+	placed, failed := db.placed[0], db.placed[1]
+	if placed.Path != filepath.Join(dir, "Alien.1979.es-419.srt") || placed.Error != "" || placed.Attempts != 1 {
+		t.Errorf("placed subtitle = %+v", placed)
+	}
+	if failed.Path != "" || failed.Error != "subtitle not found" || failed.Attempts != 3 {
+		t.Errorf("failed subtitle = %+v", failed)
+	}
+}
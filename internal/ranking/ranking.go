@@ -0,0 +1,164 @@
+package ranking
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/processor-films/internal/config"
+	"github.com/xochilpili/processor-films/internal/groups"
+	"github.com/xochilpili/processor-films/internal/models"
+	"github.com/xochilpili/processor-films/internal/subtitles"
+)
+
+// Candidate is a torrent with the subtitles found for it.
+type Candidate struct {
+	Torrent models.Torrent
+	// Subtitles are the best matching online subtitles, one per wanted language missing from the
+	// torrent files, SubtitleMatch their mean match strength from 0 to 1
+	Subtitles     []models.Subtitle
+	SubtitleMatch float64
+	// Languages are the subtitle languages found in the torrent files, EmbeddedSubtitles is true
+	// when the torrent files include subtitles in any language
+	Languages         subtitles.Languages
+	EmbeddedSubtitles bool
+	// Wanted are the configured subtitle languages in priority order
+	Wanted []models.SubtitleLanguage
+	// Profile is the quality profile of the run, its preferences take precedence over config.Ranking
+	Profile *models.QualityProfile
+}
+
+// HasSubtitles returns true when the candidate has subtitles from any source.
+func (c Candidate) HasSubtitles() bool {
+	return c.EmbeddedSubtitles || len(c.Subtitles) > 0
+}
+
+// SubtitleLanguages returns the languages of the torrent files and the online subtitles.
+func (c Candidate) SubtitleLanguages() subtitles.Languages {
+	languages := subtitles.Languages{}
+	for language := range c.Languages {
+		languages[language] = true
+	}
+	for _, subtitle := range c.Subtitles {
+		if subtitle.Language != "" {
+			languages[subtitle.Language] = true
+		}
+	}
+	return languages
+}
+
+// Satisfied returns true when the candidate covers every wanted language.
+func (c Candidate) Satisfied() bool {
+	return subtitles.Covers(c.Wanted, c.SubtitleLanguages())
+}
+
+// EmbeddedSatisfied returns true when the torrent files alone cover every wanted language.
+func (c Candidate) EmbeddedSatisfied() bool {
+	return c.EmbeddedSubtitles && subtitles.Covers(c.Wanted, c.Languages)
+}
+
+// Scorer scores a single aspect of a candidate between 0 and 1.
+type Scorer interface {
+	Name() string
+	Score(c Candidate) float64
+}
+
+type Score struct {
+	Total     float64            `json:"total"`
+	Breakdown map[string]float64 `json:"breakdown"`
+}
+
+type Ranked struct {
+	Candidate
+	Score Score
+}
+
+type weighted struct {
+	scorer Scorer
+	weight float64
+}
+
+type Ranker struct {
+	config  *config.Config
+	logger  *zerolog.Logger
+	scorers []weighted
+}
+
+// New returns a ranker with the built-in scorers weighted by config.Ranking.Weights,
+// scorers without weight are not registered. Release groups are compared through aliases.
+func New(config *config.Config, logger *zerolog.Logger, aliases *groups.Aliases) *Ranker {
+	r := &Ranker{
+		config: config,
+		logger: logger,
+	}
+	for _, scorer := range []Scorer{
+		&SubtitlesScorer{},
+		&SeedsScorer{},
+		&ResolutionScorer{Preferred: config.Ranking.Resolutions},
+		&CodecScorer{Preferred: config.Ranking.Codecs},
+		&GroupScorer{Reputation: config.Ranking.Groups, Groups: aliases},
+		&SizeScorer{Min: config.Ranking.MinSize, Max: config.Ranking.MaxSize},
+	} {
+		r.Register(scorer, config.Ranking.Weights[scorer.Name()])
+	}
+	return r
+}
+
+// Register adds a scorer, scorers with the same name are replaced.
+func (r *Ranker) Register(scorer Scorer, weight float64) {
+	if weight <= 0 {
+		return
+	}
+	for i, w := range r.scorers {
+		if w.scorer.Name() == scorer.Name() {
+			r.scorers[i] = weighted{scorer: scorer, weight: weight}
+			return
+		}
+	}
+	r.scorers = append(r.scorers, weighted{scorer: scorer, weight: weight})
+}
+
+// Rank scores every candidate and returns them sorted from best to worst, the total is the weighted
+// average of the scorers so it stays between 0 and 1.
+func (r *Ranker) Rank(candidates []Candidate) []Ranked {
+	var totalWeight float64
+	for _, w := range r.scorers {
+		totalWeight += w.weight
+	}
+
+	ranked := make([]Ranked, 0, len(candidates))
+	for _, c := range candidates {
+		score := Score{Breakdown: make(map[string]float64, len(r.scorers))}
+		for _, w := range r.scorers {
+			value := w.scorer.Score(c)
+			score.Breakdown[w.scorer.Name()] = value
+			score.Total += value * w.weight
+		}
+		if totalWeight > 0 {
+			score.Total /= totalWeight
+		}
+		ranked = append(ranked, Ranked{Candidate: c, Score: score})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score.Total > ranked[j].Score.Total
+	})
+
+	for _, rc := range ranked {
+		r.logger.Info().Msgf("torrent %s scored %.3f: %s", rc.Torrent.Title, rc.Score.Total, rc.Score)
+	}
+	return ranked
+}
+
+func (s Score) String() string {
+	names := make([]string, 0, len(s.Breakdown))
+	for name := range s.Breakdown {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=%.2f", name, s.Breakdown[name]))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,152 @@
+package ranking
+
+import (
+	"math"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/processor-films/internal/config"
+	"github.com/xochilpili/processor-films/internal/groups"
+	"github.com/xochilpili/processor-films/internal/models"
+	"github.com/xochilpili/processor-films/internal/subtitles"
+)
+
+var testWeights = map[string]float64{"subtitles": 5, "seeds": 2, "resolution": 2, "codec": 1, "group": 1, "size": 1}
+
+func newTestRanker() *Ranker {
+	logger := zerolog.Nop()
+	return New(&config.Config{Ranking: config.Ranking{
+		Weights:     testWeights,
+		Resolutions: []string{"1080p", "720p"},
+		Codecs:      []string{"x264", "x265"},
+		Groups:      map[string]float64{"yts": 1, "evo": 0.4},
+		MinSize:     700 << 20,
+		MaxSize:     4 << 30,
+	}}, &logger, groups.New(nil))
+}
+
+func candidate(title string, change func(t *models.Torrent)) Candidate {
+	torrent := models.Torrent{Title: title, Resolution: "1080p", Codec: "x264", Group: "YTS", Seeds: 100, Peers: 20, Size: "1.8 GB"}
+	change(&torrent)
+	return Candidate{
+		Torrent:           torrent,
+		Wanted:            []models.SubtitleLanguage{{Code: "es"}},
+		Languages:         subtitles.Languages{"es": true},
+		EmbeddedSubtitles: true,
+	}
+}
+
+func TestRankOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		better func(t *models.Torrent)
+		worse  func(t *models.Torrent)
+	}{
+		{"seeds", func(t *models.Torrent) { t.Seeds = 900 }, func(t *models.Torrent) { t.Seeds = 3 }},
+		{"codec", func(t *models.Torrent) { t.Codec = "x264" }, func(t *models.Torrent) { t.Codec = "x265" }},
+		{"unknown codec", func(t *models.Torrent) { t.Codec = "" }, func(t *models.Torrent) { t.Codec = "xvid" }},
+		{"size", func(t *models.Torrent) { t.Size = "2.1 GB" }, func(t *models.Torrent) { t.Size = "350 MB" }},
+		{"oversized", func(t *models.Torrent) { t.Size = "3 GB" }, func(t *models.Torrent) { t.Size = "16 GB" }},
+		{"resolution", func(t *models.Torrent) { t.Resolution = "1080p" }, func(t *models.Torrent) { t.Resolution = "2160p" }},
+		{"group", func(t *models.Torrent) { t.Group = "YTS" }, func(t *models.Torrent) { t.Group = "EVO" }},
+	}
+	r := newTestRanker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the worse candidate goes first so the order is not kept by the stable sort
+			ranked := r.Rank([]Candidate{candidate("worse", tt.worse), candidate("better", tt.better)})
+			if ranked[0].Torrent.Title != "better" {
+				t.Errorf("Rank() first = %s (%.3f), second = %s (%.3f)", ranked[0].Torrent.Title, ranked[0].Score.Total,
+					ranked[1].Torrent.Title, ranked[1].Score.Total)
+			}
+		})
+	}
+}
+
+func TestRankSubtitlesOutweighQuality(t *testing.T) {
+	r := newTestRanker()
+	withoutSubtitles := candidate("without subtitles", func(t *models.Torrent) { t.Seeds = 1000 })
+	withoutSubtitles.Languages = subtitles.Languages{}
+	withoutSubtitles.EmbeddedSubtitles = false
+	withSubtitles := candidate("with subtitles", func(t *models.Torrent) { t.Seeds = 5; t.Codec = "x265" })
+
+	ranked := r.Rank([]Candidate{withoutSubtitles, withSubtitles})
+	if ranked[0].Torrent.Title != "with subtitles" {
+		t.Errorf("Rank() first = %s", ranked[0].Torrent.Title)
+	}
+}
+
+func TestRankBreakdown(t *testing.T) {
+	r := newTestRanker()
+	ranked := r.Rank([]Candidate{
+		candidate("a", func(t *models.Torrent) {}),
+		candidate("b", func(t *models.Torrent) { t.Seeds = 0; t.Peers = 0; t.Size = "" }),
+		candidate("c", func(t *models.Torrent) { t.Resolution = "720p"; t.Group = "unknown"; t.Codec = "x265" }),
+	})
+	var weights float64
+	for _, weight := range testWeights {
+		weights += weight
+	}
+	for _, rc := range ranked {
+		if len(rc.Score.Breakdown) != len(testWeights) {
+			t.Errorf("%s breakdown = %v, want every scorer", rc.Torrent.Title, rc.Score.Breakdown)
+		}
+		var total float64
+		for name, value := range rc.Score.Breakdown {
+			if value < 0 || value > 1 {
+				t.Errorf("%s %s score = %f, want between 0 and 1", rc.Torrent.Title, name, value)
+			}
+			total += value * testWeights[name]
+		}
+		if total /= weights; math.Abs(total-rc.Score.Total) > 1e-9 {
+			t.Errorf("%s total = %f, breakdown adds up to %f", rc.Torrent.Title, rc.Score.Total, total)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		value string
+		size  int64
+		ok    bool
+	}{
+		{"1.5 GB", 3 << 29, true},
+		{"700MB", 700 << 20, true},
+		{"1,024 KiB", 1 << 20, true},
+		{" 2 tb ", 2 << 40, true},
+		{"", 0, false},
+		{"GB", 0, false},
+		{"12 parsecs", 0, false},
+	}
+	for _, tt := range tests {
+		size, ok := ParseSize(tt.value)
+		if size != tt.size || ok != tt.ok {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d, %v", tt.value, size, ok, tt.size, tt.ok)
+		}
+	}
+}
+
+func TestGroupScorer(t *testing.T) {
+	s := &GroupScorer{Reputation: map[string]float64{"rarbg": 0.9, "EVO": 0.4}, Groups: groups.New(map[string]string{"sparks-hd": "sparks"})}
+	profile := &models.QualityProfile{PreferredGroups: []string{"yts", "SPARKS"}}
+	tests := []struct {
+		group   string
+		profile *models.QualityProfile
+		want    float64
+	}{
+		{"YIFY", profile, 1},
+		{"[YTS.MX]", profile, 1},
+		{"Sparks-HD", profile, 1},
+		{"YIFY", nil, 0.5},
+		{"RARBGx", profile, 0.9},
+		{"evo", nil, 0.4},
+		{"unknown", profile, 0.5},
+		{"", profile, 0.5},
+	}
+	for _, tt := range tests {
+		c := Candidate{Torrent: models.Torrent{Group: tt.group}, Profile: tt.profile}
+		if got := s.Score(c); got != tt.want {
+			t.Errorf("Score(%q, profile %v) = %f, want %f", tt.group, tt.profile != nil, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package ranking
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/xochilpili/processor-films/internal/groups"
+	"github.com/xochilpili/processor-films/internal/subtitles"
+)
+
+// SubtitlesScorer scores the coverage of the wanted languages weighted by priority, online subtitles
+// count by their match strength and embedded subtitles in other languages score 0.1.
+type SubtitlesScorer struct{}
+
+func (s *SubtitlesScorer) Name() string { return "subtitles" }
+
+func (s *SubtitlesScorer) Score(c Candidate) float64 {
+	score := subtitles.Coverage(c.Wanted, c.Languages)
+	if len(c.Subtitles) > 0 {
+		online := subtitles.Coverage(c.Wanted, c.SubtitleLanguages()) - score
+		score += online * c.SubtitleMatch
+	}
+	if score == 0 && c.EmbeddedSubtitles {
+		return 0.1
+	}
+	return score
+}
+
+// SeedsScorer uses a logarithmic scale, 1000 seeds or more scores 1, peers count as a tenth of a seed.
+type SeedsScorer struct{}
+
+func (s *SeedsScorer) Name() string { return "seeds" }
+
+func (s *SeedsScorer) Score(c Candidate) float64 {
+	swarm := float64(c.Torrent.Seeds) + float64(c.Torrent.Peers)/10
+	return math.Min(1, math.Log10(swarm+1)/3)
+}
+
+// ResolutionScorer scores by position in the preferred list, unlisted resolutions score 0.
+type ResolutionScorer struct {
+	Preferred []string
+}
+
+func (s *ResolutionScorer) Name() string { return "resolution" }
+
+func (s *ResolutionScorer) Score(c Candidate) float64 {
+	if c.Profile != nil && len(c.Profile.Resolutions) > 0 {
+		return positionScore(c.Profile.Resolutions, c.Torrent.Resolution)
+	}
+	return positionScore(s.Preferred, c.Torrent.Resolution)
+}
+
+// CodecScorer scores by position in the preferred list, unknown codecs score 0.5 as they are usually x264.
+type CodecScorer struct {
+	Preferred []string
+}
+
+func (s *CodecScorer) Name() string { return "codec" }
+
+func (s *CodecScorer) Score(c Candidate) float64 {
+	if c.Torrent.Codec == "" {
+		return 0.5
+	}
+	if c.Profile != nil && len(c.Profile.Codecs) > 0 {
+		return positionScore(c.Profile.Codecs, c.Torrent.Codec)
+	}
+	return positionScore(s.Preferred, c.Torrent.Codec)
+}
+
+// GroupScorer uses the configured reputation of the release group, unknown groups score 0.5
+// and groups preferred by the candidate profile score 1. Groups are compared by their canonical
+// name, e.g. a torrent by YIFY is preferred by a profile preferring yts.
+type GroupScorer struct {
+	Reputation map[string]float64
+	Groups     *groups.Aliases
+}
+
+func (s *GroupScorer) Name() string { return "group" }
+
+func (s *GroupScorer) Score(c Candidate) float64 {
+	if c.Torrent.Group == "" {
+		return 0.5
+	}
+	group := s.Groups.Canonical(c.Torrent.Group)
+	if c.Profile != nil {
+		for _, preferred := range c.Profile.PreferredGroups {
+			if s.Groups.Canonical(preferred) == group {
+				return 1
+			}
+		}
+	}
+	for name, reputation := range s.Reputation {
+		if s.Groups.Canonical(name) == group {
+			return math.Max(0, math.Min(1, reputation))
+		}
+	}
+	return 0.5
+}
+
+// SizeScorer scores 1 inside the [Min, Max] bytes range and decays proportionally outside of it,
+// unknown sizes score 0.5. The candidate profile limits take precedence when set.
+type SizeScorer struct {
+	Min int64
+	Max int64
+}
+
+func (s *SizeScorer) Name() string { return "size" }
+
+func (s *SizeScorer) Score(c Candidate) float64 {
+	size, ok := ParseSize(c.Torrent.Size)
+	if !ok {
+		return 0.5
+	}
+	min, max := s.Min, s.Max
+	if c.Profile != nil {
+		if c.Profile.MinSize > 0 {
+			min = c.Profile.MinSize
+		}
+		if c.Profile.MaxSize > 0 {
+			max = c.Profile.MaxSize
+		}
+	}
+	switch {
+	case min > 0 && size < min:
+		return float64(size) / float64(min)
+	case max > 0 && size > max:
+		return float64(max) / float64(size)
+	}
+	return 1
+}
+
+func positionScore(preferred []string, value string) float64 {
+	for i, p := range preferred {
+		if strings.EqualFold(p, value) {
+			return 1 - float64(i)/float64(len(preferred))
+		}
+	}
+	return 0
+}
+
+var sizeUnits = map[string]float64{
+	"b":   1,
+	"kb":  1 << 10,
+	"kib": 1 << 10,
+	"mb":  1 << 20,
+	"mib": 1 << 20,
+	"gb":  1 << 30,
+	"gib": 1 << 30,
+	"tb":  1 << 40,
+	"tib": 1 << 40,
+}
+
+// ParseSize parses human readable sizes as returned by torrent-api, e.g. "1.4 GB" or "700MB".
+func ParseSize(value string) (int64, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	i := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != ','
+	})
+	if i <= 0 {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(strings.ReplaceAll(value[:i], ",", ""), 64)
+	if err != nil {
+		return 0, false
+	}
+	unit, ok := sizeUnits[strings.TrimSpace(value[i:])]
+	if !ok {
+		return 0, false
+	}
+	return int64(number * unit), true
+}
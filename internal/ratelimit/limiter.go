@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+)
+
+// Limiter bounds the number of in-flight requests and the requests per second sent to an upstream.
+type Limiter struct {
+	Name string
+	sem  chan struct{}
+	rate *rate.Limiter
+}
+
+// New returns a limiter for the upstream, a concurrency or rps lower or equal than zero disables that limit.
+func New(name string, concurrency int, rps float64) *Limiter {
+	l := &Limiter{Name: name}
+	if concurrency > 0 {
+		l.sem = make(chan struct{}, concurrency)
+	}
+	if rps > 0 {
+		burst := int(rps)
+		if burst < 1 {
+			burst = 1
+		}
+		l.rate = rate.NewLimiter(rate.Limit(rps), burst)
+	}
+	return l
+}
+
+// Acquire blocks until a request slot is available, the returned func must be called once the request is done.
+func (l *Limiter) Acquire(ctx context.Context) (func(), error) {
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if l.sem != nil {
+			<-l.sem
+		}
+	}
+	if l.rate != nil {
+		if err := l.rate.Wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
@@ -0,0 +1,132 @@
+package release
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var accents = map[rune]rune{
+	'á': 'a', 'à': 'a', 'ä': 'a', 'â': 'a', 'ã': 'a', 'å': 'a',
+	'é': 'e', 'è': 'e', 'ë': 'e', 'ê': 'e',
+	'í': 'i', 'ì': 'i', 'ï': 'i', 'î': 'i',
+	'ó': 'o', 'ò': 'o', 'ö': 'o', 'ô': 'o', 'õ': 'o', 'ø': 'o',
+	'ú': 'u', 'ù': 'u', 'ü': 'u', 'û': 'u',
+	'ñ': 'n', 'ç': 'c', 'ý': 'y', 'ÿ': 'y',
+}
+
+// articles are dropped in every language films are released in, e.g. "The Substance" and "Substance".
+var articles = map[string]bool{
+	"the": true, "a": true, "an": true,
+	"el": true, "la": true, "los": true, "las": true,
+	"le": true, "les": true, "l": true, "il": true, "lo": true,
+	"der": true, "die": true, "das": true,
+}
+
+var romanNumerals = map[string]string{
+	"ii": "2", "iii": "3", "iv": "4", "vi": "6", "vii": "7", "viii": "8", "ix": "9",
+	"xi": "11", "xii": "12", "xiii": "13", "xiv": "14", "xv": "15", "xvi": "16", "xvii": "17", "xviii": "18", "xix": "19", "xx": "20",
+}
+
+// letterNumerals are also letters in titles, e.g. Malcolm X or V for Vendetta, see TitleSimilarity.
+var letterNumerals = map[string]string{
+	"v": "5", "x": "10",
+}
+
+// NormalizeTitle returns the tokens of a title without accents, punctuation and articles,
+// roman numerals are converted to numbers, e.g. "Rocky IV" and "rocky 4" are equal. Single
+// letter numerals are kept as letters.
+func NormalizeTitle(title string) []string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		if folded, ok := accents[r]; ok {
+			r = folded
+		}
+		switch {
+		case r == '\'' || r == '’':
+			// don't, director's
+		case r == '&':
+			b.WriteString(" and ")
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	var tokens []string
+	for _, token := range strings.Fields(b.String()) {
+		if articles[token] {
+			continue
+		}
+		if number, ok := romanNumerals[token]; ok {
+			token = number
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// TitleSimilarity compares two titles from 0 to 1 once normalized, titles with different numbers
+// (sequels, e.g. "Alien" and "Alien 3") score 0. A single letter numeral ending a title is read as
+// a number when the other title ends with it, e.g. "Rocky V" and "Rocky 5".
+func TitleSimilarity(a string, b string) float64 {
+	ta, tb := NormalizeTitle(a), NormalizeTitle(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	ta, tb = letterNumeral(ta, tb), letterNumeral(tb, ta)
+	if numbers(ta) != numbers(tb) {
+		return 0
+	}
+	sa, sb := strings.Join(ta, " "), strings.Join(tb, " ")
+	if sa == sb {
+		return 1
+	}
+	ra, rb := []rune(sa), []rune(sb)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// letterNumeral converts the single letter numeral ending tokens when other ends with its number.
+func letterNumeral(tokens []string, other []string) []string {
+	last := len(tokens) - 1
+	number, ok := letterNumerals[tokens[last]]
+	if !ok || other[len(other)-1] != number {
+		return tokens
+	}
+	converted := append([]string{}, tokens[:last]...)
+	return append(converted, number)
+}
+
+func numbers(tokens []string) string {
+	var found []string
+	for _, token := range tokens {
+		if _, err := strconv.Atoi(token); err == nil {
+			found = append(found, token)
+		}
+	}
+	return strings.Join(found, " ")
+}
+
+func levenshtein(a []rune, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,52 @@
+package release
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  []string
+	}{
+		{"The Substance", []string{"substance"}},
+		{"Amélie", []string{"amelie"}},
+		{"Director's Cut: Fast & Furious", []string{"directors", "cut", "fast", "and", "furious"}},
+		{"Rocky IV", []string{"rocky", "4"}},
+		{"Star Wars: Episode III", []string{"star", "wars", "episode", "3"}},
+		{"Malcolm X", []string{"malcolm", "x"}},
+		{"V for Vendetta", []string{"v", "for", "vendetta"}},
+		{"Rocky V", []string{"rocky", "v"}},
+	}
+	for _, tt := range tests {
+		if got := NormalizeTitle(tt.title); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("NormalizeTitle(%q) = %v, want %v", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestTitleSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"The Substance", "Substance", 1},
+		{"Rocky IV", "Rocky 4", 1},
+		{"Rocky V", "Rocky 5", 1},
+		{"Rocky 5", "Rocky V", 1},
+		{"Malcolm X", "Malcolm X", 1},
+		{"V for Vendetta", "V for Vendetta", 1},
+		{"V for Vendetta", "5 for Vendetta", 0},
+		{"Malcolm X", "Malcolm 10", 1},
+		{"Malcolm X", "Malcolm 5", 0},
+		{"Alien", "Alien 3", 0},
+		{"Rocky V", "Rocky IV", 0},
+		{"", "Alien", 0},
+	}
+	for _, tt := range tests {
+		if got := TitleSimilarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("TitleSimilarity(%q, %q) = %f, want %f", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package release
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Release holds the fields parsed from a release name, e.g.
+// Movie.Title.2023.Directors.Cut.1080p.BluRay.DDP5.1.x265.HDR-GROUP.
+type Release struct {
+	Title      string   `json:"title"`
+	Year       int      `json:"year,omitempty"`
+	Resolution string   `json:"resolution,omitempty"`
+	Source     string   `json:"source,omitempty"`
+	Codec      string   `json:"codec,omitempty"`
+	Audio      []string `json:"audio,omitempty"`
+	HDR        []string `json:"hdr,omitempty"`
+	Edition    string   `json:"edition,omitempty"`
+	Languages  []string `json:"languages,omitempty"`
+	Group      string   `json:"group,omitempty"`
+}
+
+type pattern struct {
+	re    *regexp.Regexp
+	value string
+}
+
+// patterns are matched against the lower case name with separators replaced by spaces, the
+// first matching pattern of each list wins so more specific patterns go first.
+func patterns(values ...string) []pattern {
+	var list []pattern
+	for i := 0; i < len(values); i += 2 {
+		list = append(list, pattern{re: regexp.MustCompile(`(?:^|\s)(?:` + values[i] + `)(?:\s|$)`), value: values[i+1]})
+	}
+	return list
+}
+
+var resolutions = patterns(
+	`2160p|4k|uhd`, "2160p",
+	`1080p|1080i`, "1080p",
+	`720p`, "720p",
+	`576p`, "576p",
+	`480p`, "480p",
+)
+
+// streaming services (AMZN, NF, DSNP) tag both WEB-DL and WEBRip releases so they say nothing of the source
+var sources = patterns(
+	`web-?dl|web`, "WEB-DL",
+	`web-?rip`, "WEBRip",
+	`blu-?ray|bd-?rip|br-?rip|bd-?remux|bdmv`, "BluRay",
+	`hdtv|pdtv`, "HDTV",
+	`dvd-?rip|dvd-?scr|dvd`, "DVDRip",
+	`hd-?rip`, "HDRip",
+	`hd-?cam|cam-?rip|cam`, "CAM",
+	`hd-?ts|telesync|ts`, "TS",
+	`telecine|tc`, "TC",
+)
+
+var codecs = patterns(
+	`x ?265|h ?265|hevc`, "x265",
+	`x ?264|h ?264|avc`, "x264",
+	`xvid|divx`, "XviD",
+	`av1`, "AV1",
+	`vp9`, "VP9",
+)
+
+var audios = patterns(
+	`truehd`, "TrueHD",
+	`atmos`, "Atmos",
+	`dts-?hd(?: ?ma)?`, "DTS-HD",
+	`dts`, "DTS",
+	`ddp ?[257] [01]|dd\+ ?[257] [01]|ddp|eac3|e-ac-?3`, "DDP",
+	`dd ?[257] [01]|ac3|dd`, "DD",
+	`aac(?: ?[257] [01])?`, "AAC",
+	`flac`, "FLAC",
+	`mp3`, "MP3",
+)
+
+var hdrs = patterns(
+	`hdr10\+|hdr10plus`, "HDR10+",
+	`hdr10`, "HDR10",
+	`hdr`, "HDR",
+	`dv|dovi|dolby vision`, "DV",
+	`hlg`, "HLG",
+)
+
+var editions = patterns(
+	`director'?s cut|directors cut`, "Director's Cut",
+	`extended(?: cut| edition)?`, "Extended",
+	`theatrical(?: cut)?`, "Theatrical",
+	`unrated`, "Unrated",
+	`uncut`, "Uncut",
+	`remastered`, "Remastered",
+	`imax`, "IMAX",
+	`criterion`, "Criterion",
+	`final cut`, "Final Cut",
+)
+
+var languages = patterns(
+	`latino`, "es-419",
+	`castellano`, "es-ES",
+	`spanish|spa|esp`, "es",
+	`english|eng`, "en",
+	`truefrench|french|vff|vfq`, "fr",
+	`ita|italian`, "it",
+	`ger|german`, "de",
+	`multi`, "multi",
+	`dual(?: audio)?`, "dual",
+)
+
+var yearRe = regexp.MustCompile(`\b((?:19|20)\d{2})\b`)
+var separatorRe = regexp.MustCompile(`[._\[\](){}]`)
+var groupRe = regexp.MustCompile(`-\s*([A-Za-z0-9]+)\s*$`)
+var hyphenTagRe = regexp.MustCompile(`(?:^|\s)(?:web|blu|dts|hd|cam|dvd|bd|br|e)-(?:dl|rip|ray|hd|ts|cam|ac3|remux)\s*$`)
+var leadingGroupRe = regexp.MustCompile(`^\s*\[([^\]]+)\]`)
+var trailingGroupRe = regexp.MustCompile(`\[([A-Za-z0-9]+)(?:\.[A-Za-z]{2,3})?\]\s*$`)
+var videoExtRe = regexp.MustCompile(`(?i)\.(mkv|mp4|avi|m4v|mov|wmv|ts)$`)
+
+// findLast returns the pattern matching at the very end of value and where its match starts, -1 when none does.
+func findLast(list []pattern, value string) (string, int) {
+	trimmed := strings.TrimRight(value, " ")
+	for _, p := range list {
+		matches := p.re.FindAllStringIndex(trimmed, -1)
+		if len(matches) > 0 && matches[len(matches)-1][1] == len(trimmed) {
+			return p.value, matches[len(matches)-1][0]
+		}
+	}
+	return "", -1
+}
+
+// Parse parses a raw release name, fields which are not found are left empty.
+func Parse(name string) Release {
+	var r Release
+	name = videoExtRe.ReplaceAllString(strings.TrimSpace(name), "")
+	if m := leadingGroupRe.FindStringSubmatchIndex(name); m != nil {
+		r.Group = name[m[2]:m[3]]
+		name = name[m[1]:]
+	}
+
+	// separators are replaced by spaces keeping the length so indexes map back to the name
+	normalized := separatorRe.ReplaceAllString(name, " ")
+	lower := strings.ToLower(normalized)
+	if len(lower) != len(normalized) {
+		normalized = lower
+	}
+	// the group suffix is hidden from the tags as it may look like one, e.g. -DTS
+	suffix := groupRe.FindStringSubmatchIndex(lower)
+	if suffix != nil && hyphenTagRe.MatchString(lower) {
+		// hyphenated tags at the end are not groups, e.g. WEB-DL
+		suffix = nil
+	}
+	if suffix != nil {
+		lower = lower[:suffix[0]] + strings.Repeat(" ", len(lower)-suffix[0])
+	}
+
+	find := func(list []pattern, from int) (string, int) {
+		for _, p := range list {
+			if loc := p.re.FindStringIndex(lower[from:]); loc != nil {
+				return p.value, from + loc[0]
+			}
+		}
+		return "", -1
+	}
+	findAll := func(list []pattern, from int) []string {
+		var values []string
+		for _, p := range list {
+			if p.re.MatchString(lower[from:]) {
+				values = append(values, p.value)
+			}
+		}
+		return values
+	}
+
+	// the title ends at the year or at the first resolution or codec, the other tags are common
+	// words in titles (The Italian Job, Charlottes Web) so they are only looked for after it.
+	// Tags at the very start are part of the title, e.g. "4K" or "CAM" films.
+	end := len(lower)
+	var i int
+	r.Resolution, i = find(resolutions, 0)
+	if i > 0 {
+		end = i
+	}
+	r.Codec, i = find(codecs, 0)
+	if i > 0 && i < end {
+		end = i
+	}
+	// the year closest to the technical tags ends the title, so titles like 2001 or 1917 are kept,
+	// years not released yet are part of the title, e.g. Blade Runner 2049
+	for _, loc := range yearRe.FindAllStringSubmatchIndex(lower[:end], -1) {
+		year, _ := strconv.Atoi(lower[loc[2]:loc[3]])
+		if loc[2] > 0 && year <= time.Now().Year()+1 {
+			r.Year = year
+			end = loc[2]
+		}
+	}
+	if end < len(lower) {
+		r.Source, _ = find(sources, end)
+		r.Audio = findAll(audios, end)
+		r.HDR = findAll(hdrs, end)
+		r.Edition, _ = find(editions, end)
+		r.Languages = findAll(languages, end)
+	}
+	titleEnd := end
+	// editions right before the year or the tags are not part of the title, e.g. Alien.Directors.Cut.1979
+	for titleEnd < len(lower) {
+		edition, start := findLast(editions, lower[:titleEnd])
+		if start <= 0 {
+			break
+		}
+		if r.Edition == "" {
+			r.Edition = edition
+		}
+		titleEnd = start
+	}
+	if titleEnd == len(lower) && suffix != nil {
+		// without tags the suffix is part of the title, e.g. Spider-Man
+		suffix = nil
+		titleEnd = len(normalized)
+	}
+
+	switch {
+	case suffix != nil:
+		r.Group = normalized[suffix[2]:suffix[3]]
+	case r.Group == "":
+		if m := trailingGroupRe.FindStringSubmatch(name); m != nil {
+			r.Group = m[1]
+		}
+	}
+	r.Title = strings.Join(strings.Fields(strings.Trim(normalized[:titleEnd], " -")), " ")
+	return r
+}
@@ -0,0 +1,42 @@
+package release
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		want Release
+	}{
+		{"The.Italian.Job.2003.1080p.BluRay.x264-YTS", Release{Title: "The Italian Job", Year: 2003, Resolution: "1080p", Source: "BluRay", Codec: "x264", Group: "YTS"}},
+		{"The.English.Patient.1996.720p.BluRay.x264-SPARKS", Release{Title: "The English Patient", Year: 1996, Resolution: "720p", Source: "BluRay", Codec: "x264", Group: "SPARKS"}},
+		{"The.French.Dispatch.2021.1080p.WEBRip.x265-RARBG", Release{Title: "The French Dispatch", Year: 2021, Resolution: "1080p", Source: "WEBRip", Codec: "x265", Group: "RARBG"}},
+		{"The.Spanish.Prisoner.1997.1080p.WEB-DL.DD5.1.H264-FGT", Release{Title: "The Spanish Prisoner", Year: 1997, Resolution: "1080p", Source: "WEB-DL", Codec: "x264", Audio: []string{"DD"}, Group: "FGT"}},
+		{"Charlottes.Web.2006.1080p.BluRay", Release{Title: "Charlottes Web", Year: 2006, Resolution: "1080p", Source: "BluRay"}},
+		{"Italian.Race.2016.ITALIAN.1080p.WEB-DL.x264", Release{Title: "Italian Race", Year: 2016, Resolution: "1080p", Source: "WEB-DL", Codec: "x264", Languages: []string{"it"}}},
+		{"The.Dark.Knight.2008.LATINO.1080p.BluRay.x264", Release{Title: "The Dark Knight", Year: 2008, Resolution: "1080p", Source: "BluRay", Codec: "x264", Languages: []string{"es-419"}}},
+		{"Cam.2018.1080p.NF.WEB-DL.DDP5.1.x264-NTG", Release{Title: "Cam", Year: 2018, Resolution: "1080p", Source: "WEB-DL", Codec: "x264", Audio: []string{"DDP"}, Group: "NTG"}},
+		{"Movie.Title.2023.Directors.Cut.1080p.BluRay.DDP5.1.x265.HDR-GROUP", Release{Title: "Movie Title", Year: 2023, Resolution: "1080p", Source: "BluRay", Codec: "x265", Audio: []string{"DDP"}, HDR: []string{"HDR"}, Edition: "Director's Cut", Group: "GROUP"}},
+		{"Spider-Man.No.Way.Home.2021.2160p.WEB-DL.DDP5.1.Atmos.DV.HEVC-CMRG", Release{Title: "Spider-Man No Way Home", Year: 2021, Resolution: "2160p", Source: "WEB-DL", Codec: "x265", Audio: []string{"Atmos", "DDP"}, HDR: []string{"DV"}, Group: "CMRG"}},
+		{"2001.A.Space.Odyssey.1968.1080p.BluRay.x264-AMIABLE", Release{Title: "2001 A Space Odyssey", Year: 1968, Resolution: "1080p", Source: "BluRay", Codec: "x264", Group: "AMIABLE"}},
+		{"Blade.Runner.2049.2017.1080p.BluRay.x264-SPARKS", Release{Title: "Blade Runner 2049", Year: 2017, Resolution: "1080p", Source: "BluRay", Codec: "x264", Group: "SPARKS"}},
+		{"1917 (2019) [1080p] [BluRay] [5.1] [YTS.MX]", Release{Title: "1917", Year: 2019, Resolution: "1080p", Source: "BluRay", Group: "YTS"}},
+		{"[YTS] The Hunt 2020 720p WEBRip", Release{Title: "The Hunt", Year: 2020, Resolution: "720p", Source: "WEBRip", Group: "YTS"}},
+		{"Spider-Man", Release{Title: "Spider-Man"}},
+		{"Movie.2023.1080p.AMZN.WEBRip.DDP5.1.x264-NTb", Release{Title: "Movie", Year: 2023, Resolution: "1080p", Source: "WEBRip", Codec: "x264", Audio: []string{"DDP"}, Group: "NTb"}},
+		{"Movie.2023.1080p.NF.WEB-DL.DDP5.1.H.264-FLUX", Release{Title: "Movie", Year: 2023, Resolution: "1080p", Source: "WEB-DL", Codec: "x264", Audio: []string{"DDP"}, Group: "FLUX"}},
+		{"Movie.2022.2160p.DSNP.WEBRip.x265-NTb", Release{Title: "Movie", Year: 2022, Resolution: "2160p", Source: "WEBRip", Codec: "x265", Group: "NTb"}},
+		{"Alien.Directors.Cut.1979.1080p.BluRay.x264-GROUP", Release{Title: "Alien", Year: 1979, Resolution: "1080p", Source: "BluRay", Codec: "x264", Edition: "Director's Cut", Group: "GROUP"}},
+		{"Aliens.Extended.Remastered.1986.720p.BluRay.x264", Release{Title: "Aliens", Year: 1986, Resolution: "720p", Source: "BluRay", Codec: "x264", Edition: "Remastered"}},
+		{"Blade.Runner.2049.1080p.BluRay.x264-SPARKS", Release{Title: "Blade Runner 2049", Resolution: "1080p", Source: "BluRay", Codec: "x264", Group: "SPARKS"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.name); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
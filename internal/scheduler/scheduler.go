@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/processor-films/internal/config"
+	"github.com/xochilpili/processor-films/internal/models"
+	"github.com/xochilpili/processor-films/internal/processor"
+)
+
+type Runner interface {
+	Run(ctx context.Context, opType models.OperationType, opts models.RunOptions) (*models.Job, error)
+}
+
+type entry struct {
+	id       cron.EntryID
+	schedule models.Schedule
+}
+
+type Scheduler struct {
+	config  *config.Config
+	logger  *zerolog.Logger
+	runner  Runner
+	cron    *cron.Cron
+	mu      sync.Mutex
+	entries map[models.OperationType]*entry
+}
+
+func New(config *config.Config, logger *zerolog.Logger, runner Runner) *Scheduler {
+	return &Scheduler{
+		config: config,
+		logger: logger,
+		runner: runner,
+		cron:   cron.New(),
+		entries: map[models.OperationType]*entry{
+			models.FESTIVALS: {schedule: models.Schedule{OperationType: models.FESTIVALS.String(), Expression: config.Scheduler.Festivals}},
+			models.POPULAR:   {schedule: models.Schedule{OperationType: models.POPULAR.String(), Expression: config.Scheduler.Popular}},
+		},
+	}
+}
+
+// Start registers every operation type with a cron expression and starts the scheduler,
+// nothing is scheduled when the scheduler is disabled.
+func (s *Scheduler) Start() error {
+	if !s.config.Scheduler.Enabled {
+		s.logger.Info().Msg("scheduler is disabled")
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for opType, e := range s.entries {
+		if e.schedule.Expression == "" {
+			continue
+		}
+		opType := opType
+		id, err := s.cron.AddFunc(e.schedule.Expression, func() { s.run(opType) })
+		if err != nil {
+			s.logger.Err(err).Msgf("invalid cron expression for %s: %s", opType.String(), e.schedule.Expression)
+			return err
+		}
+		e.id = id
+		e.schedule.Enabled = true
+		s.logger.Info().Msgf("scheduled %s with expression: %s", opType.String(), e.schedule.Expression)
+	}
+	s.cron.Start()
+	return nil
+}
+
+// Stop stops the scheduler, running jobs are not interrupted.
+func (s *Scheduler) Stop() {
+	<-s.cron.Stop().Done()
+}
+
+func (s *Scheduler) Schedules() []models.Schedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	schedules := []models.Schedule{}
+	for _, opType := range []models.OperationType{models.FESTIVALS, models.POPULAR} {
+		e := s.entries[opType]
+		schedule := e.schedule
+		if schedule.Enabled {
+			next := s.cron.Entry(e.id).Next
+			if !next.IsZero() {
+				schedule.NextRun = &next
+			}
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules
+}
+
+func (s *Scheduler) run(opType models.OperationType) {
+	if s.config.Scheduler.Jitter > 0 {
+		// spread runs so replicas and operation types do not hit upstreams at the same time
+		time.Sleep(time.Duration(rand.Int63n(int64(s.config.Scheduler.Jitter))))
+	}
+
+	startedAt := time.Now().UTC()
+	job, err := s.runner.Run(context.Background(), opType, models.RunOptions{Provider: "all"})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entries[opType]
+	e.schedule.LastRun = &startedAt
+	e.schedule.LastError = ""
+	if err != nil {
+		e.schedule.LastError = err.Error()
+		if errors.Is(err, processor.ErrRunInProgress) {
+			s.logger.Warn().Msgf("skipping scheduled %s run, a run is already in progress", opType.String())
+			return
+		}
+		s.logger.Err(err).Msgf("error while starting scheduled %s run", opType.String())
+		return
+	}
+	e.schedule.LastJobId = job.Id
+	s.logger.Info().Msgf("scheduled %s run started as job %d", opType.String(), job.Id)
+}
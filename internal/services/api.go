@@ -2,8 +2,10 @@ package services
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"mime"
+	"strconv"
+	"strings"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/rs/zerolog"
@@ -11,21 +13,44 @@ import (
 	"github.com/xochilpili/processor-films/internal/models"
 )
 
+const (
+	TORRENT_API          = "torrent-api"
+	SUBTITLER_API        = "subtitler-api"
+	TORRENT_METADATA_API = "torrent-metadata-api"
+	DOWNLOAD_CLIENT      = "download-client"
+	TORRENT_FILE         = "torrent-file"
+)
+
 type Api struct {
-	config *config.Config
-	logger *zerolog.Logger
-	r      *resty.Client
+	config    *config.Config
+	logger    *zerolog.Logger
+	upstreams map[string]*upstream
 }
 
 func NewApi(config *config.Config, logger *zerolog.Logger) *Api {
-	r := resty.New()
 	return &Api{
 		config: config,
 		logger: logger,
-		r:      r,
+		upstreams: map[string]*upstream{
+			TORRENT_API:          newUpstream(TORRENT_API, config.TorrentApi, config.TorrentApi.Retries, config.Debug, logger),
+			SUBTITLER_API:        newUpstream(SUBTITLER_API, config.SubtitlerApi, config.SubtitlerApi.Retries, config.Debug, logger),
+			TORRENT_METADATA_API: newUpstream(TORRENT_METADATA_API, config.TorrentMetadataApi, config.TorrentMetadataApi.Retries, config.Debug, logger),
+			TORRENT_FILE:         newUpstream(TORRENT_FILE, config.TorrentFile, config.TorrentFile.Retries, config.Debug, logger),
+		},
+	}
+}
+
+// ResetBreakers closes every circuit breaker, it is called when a new run starts.
+func (a *Api) ResetBreakers() {
+	for _, u := range a.upstreams {
+		u.breaker.Reset()
 	}
 }
 
+func (a *Api) do(ctx context.Context, upstream string, call func(r *resty.Request) (*resty.Response, error)) (*resty.Response, error) {
+	return a.upstreams[upstream].do(ctx, call)
+}
+
 func (a *Api) FetchTorrents(ctx context.Context, params models.FilterParams) ([]models.Torrent, error) {
 	var result models.GenericResponse[models.Torrent]
 	url := fmt.Sprintf("%s/%s/", a.config.TorrentApiUrl, params.Provider)
@@ -36,13 +61,15 @@ func (a *Api) FetchTorrents(ctx context.Context, params models.FilterParams) ([]
 		"res":  params.Resolution,
 	}
 
-	res, err := a.r.R().SetHeader("Content-Type", "application/json").SetQueryParams(queryParams).SetDebug(a.config.Debug).Get(url)
+	res, err := a.do(ctx, TORRENT_API, func(r *resty.Request) (*resty.Response, error) {
+		return r.SetHeader("Content-Type", "application/json").SetQueryParams(queryParams).Get(url)
+	})
 	if err != nil {
 		a.logger.Err(err).Msgf("error ocurred while fetching torrents for: %s, resolution: %s", params.Term, params.Resolution)
 		return nil, err
 	}
 
-	err = json.Unmarshal(res.Body(), &result)
+	err = decode(TORRENT_API, res, &result)
 	if err != nil {
 		a.logger.Err(err).Msgf("error ocurred while decode json response for torrents: %s, resolution: %s", params.Term, params.Resolution)
 		return nil, err
@@ -50,48 +77,74 @@ func (a *Api) FetchTorrents(ctx context.Context, params models.FilterParams) ([]
 	return result.Data, nil
 }
 
-func (a *Api) AddTorrent(ctx context.Context, magnetLink string) error {
-	url := fmt.Sprintf("%s/api/v2/torrents/add", a.config.TransmissionApiUrl)
-	_, err := a.r.R().SetFormData(map[string]string{
-		"urls": magnetLink,
-	}).Post(url)
-	if err != nil {
-		a.logger.Err(err).Msg("error while adding new torrent from magnet")
-		return err
-	}
-	return nil
-}
-
-func (a *Api) GetSubtitles(ctx context.Context, title string) ([]models.Subtitle, error) {
+func (a *Api) GetSubtitles(ctx context.Context, title string, language string) ([]models.Subtitle, error) {
 	var result models.GenericResponse[models.Subtitle]
-	a.logger.Info().Msgf("requesting subtitles for %s to %s", title, a.config.SubtitlerApiUrl)
-	res, err := a.r.R().SetHeader("Content-Type", "application/json").SetQueryParam("term", title).SetDebug(a.config.Debug).Get(a.config.SubtitlerApiUrl)
+	a.logger.Info().Msgf("requesting %s subtitles for %s to %s", language, title, a.config.SubtitlerApiUrl)
+	queryParams := map[string]string{
+		"term": title,
+		"lang": language,
+	}
+	res, err := a.do(ctx, SUBTITLER_API, func(r *resty.Request) (*resty.Response, error) {
+		return r.SetHeader("Content-Type", "application/json").SetQueryParams(queryParams).Get(a.config.SubtitlerApiUrl)
+	})
 	if err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal(res.Body(), &result)
+	err = decode(SUBTITLER_API, res, &result)
 	if err != nil {
 		return nil, err
 	}
 	return result.Data, nil
 }
 
+// GetTorrentMetadata returns the metadata of the torrent from the configured metadata source.
 func (a *Api) GetTorrentMetadata(ctx context.Context, torrent *models.Torrent) (*models.TorrentMetadata, error) {
+	switch a.config.MetadataSource {
+	case "local":
+		return a.localTorrentMetadata(ctx, torrent)
+	case "auto":
+		if len(torrentSources(torrent)) > 0 {
+			return a.localTorrentMetadata(ctx, torrent)
+		}
+	}
+	return a.remoteTorrentMetadata(ctx, torrent)
+}
+
+func (a *Api) remoteTorrentMetadata(ctx context.Context, torrent *models.Torrent) (*models.TorrentMetadata, error) {
 	var result models.TorrentMetadata
 	a.logger.Info().Msgf("fetching torrent metadata for %s to %s", torrent.Title, a.config.TorrentMetadataApiUrl)
-	res, err := a.r.R().
-		SetHeader("Content-Type", "application/json").
-		SetDebug(a.config.Debug).
-		SetBody(map[string]interface{}{
-			"query": torrent.Magnet,
-		}).
-		Post(a.config.TorrentMetadataApiUrl)
+	res, err := a.do(ctx, TORRENT_METADATA_API, func(r *resty.Request) (*resty.Response, error) {
+		return r.
+			SetHeader("Content-Type", "application/json").
+			SetBody(map[string]interface{}{
+				"query": torrent.Magnet,
+			}).
+			Post(a.config.TorrentMetadataApiUrl)
+	})
 	if err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal(res.Body(), &result)
+	err = decode(TORRENT_METADATA_API, res, &result)
 	if err != nil {
 		return nil, err
 	}
 	return &result, nil
 }
+
+// DownloadSubtitle downloads the subtitle file, it returns the raw content (it may be a zip archive) and its filename.
+func (a *Api) DownloadSubtitle(ctx context.Context, id int) ([]byte, string, error) {
+	url := strings.ReplaceAll(a.config.SubtitlerDownloadUrl, "{id}", strconv.Itoa(id))
+	a.logger.Info().Msgf("downloading subtitle %d from %s", id, url)
+	res, err := a.do(ctx, SUBTITLER_API, func(r *resty.Request) (*resty.Response, error) {
+		return r.Get(url)
+	})
+	if err != nil {
+		a.logger.Err(err).Msgf("error while downloading subtitle: %d", id)
+		return nil, "", err
+	}
+	var filename string
+	if _, params, err := mime.ParseMediaType(res.Header().Get("Content-Disposition")); err == nil {
+		filename = params["filename"]
+	}
+	return res.Body(), filename, nil
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/processor-films/internal/config"
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+// DownloadClient adds and follows torrents in the torrent client where films are downloaded.
+type DownloadClient interface {
+	// AddTorrent adds the magnet and returns its infohash, ErrDuplicateTorrent is returned along with
+	// the hash when the torrent already exists in the client. Adding is not idempotent so clients
+	// must not retry it.
+	AddTorrent(ctx context.Context, magnetLink string, opts models.AddTorrentOptions) (string, error)
+	Torrents(ctx context.Context, hashes []string) ([]models.BitTorrent, error)
+	ResetBreakers()
+}
+
+// NewDownloadClient returns the download client selected by config.DownloadClient.Kind.
+func NewDownloadClient(config *config.Config, logger *zerolog.Logger) DownloadClient {
+	if config.DownloadClient.Kind == TRANSMISSION {
+		return NewTransmission(config, logger)
+	}
+	return NewQBittorrent(config, logger)
+}
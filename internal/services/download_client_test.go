@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/processor-films/internal/config"
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+const testMagnet = "magnet:?xt=urn:btih:C12FE1C06BBA254A9DC9F519B335AA7C1367A88A&dn=Movie.2024"
+
+func newTestDownloadConfig(url string) *config.Config {
+	return &config.Config{
+		TransmissionApiUrl: url,
+		TransmissionApi:    config.Upstream{Concurrency: 1, Timeout: time.Second},
+		DownloadClient:     config.DownloadClient{Username: "user", Password: "secret"},
+	}
+}
+
+func TestTransmissionSessionId(t *testing.T) {
+	var mu sync.Mutex
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		if r.URL.Path != transmissionRpcPath {
+			http.NotFound(w, r)
+			return
+		}
+		if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get(transmissionSessionId) != "session-1" {
+			w.Header().Set(transmissionSessionId, "session-1")
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		var req transmissionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "torrent-add" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"result":"success","arguments":{"torrent-added":{"id":1,"name":"Movie.2024","hashString":"C12FE1C06BBA254A9DC9F519B335AA7C1367A88A"}}}`))
+	}))
+	defer server.Close()
+	logger := zerolog.Nop()
+	// the trailing slash must not end in //transmission/rpc
+	client := NewTransmission(newTestDownloadConfig(server.URL+"/"), &logger)
+
+	for i := 0; i < 2; i++ {
+		hash, err := client.AddTorrent(context.Background(), testMagnet, models.AddTorrentOptions{})
+		if err != nil {
+			t.Fatalf("AddTorrent() error = %v", err)
+		}
+		if hash != "c12fe1c06bba254a9dc9f519b335aa7c1367a88a" {
+			t.Errorf("AddTorrent() hash = %s", hash)
+		}
+	}
+	// the session id is negotiated once
+	if requests != 3 {
+		t.Errorf("requests = %d, want 3", requests)
+	}
+}
+
+func TestTransmissionUnauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	logger := zerolog.Nop()
+	client := NewTransmission(newTestDownloadConfig(server.URL), &logger)
+
+	if _, err := client.AddTorrent(context.Background(), testMagnet, models.AddTorrentOptions{}); !errors.Is(err, ErrBadResponse) {
+		t.Errorf("AddTorrent() error = %v, want ErrBadResponse", err)
+	}
+}
+
+// newQBittorrentServer serves the login and add endpoints, added torrents require the session cookie
+// of the last login, expire drops the session.
+func newQBittorrentServer(t *testing.T) (server *httptest.Server, logins *int, expire func()) {
+	t.Helper()
+	var mu sync.Mutex
+	var sid string
+	logins = new(int)
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/api/v2/auth/login":
+			if r.FormValue("username") != "user" || r.FormValue("password") != "secret" {
+				w.Write([]byte("Fails."))
+				return
+			}
+			*logins++
+			sid = time.Now().String()
+			http.SetCookie(w, &http.Cookie{Name: "SID", Value: sid, Path: "/"})
+			w.Write([]byte("Ok."))
+		case "/api/v2/torrents/add":
+			if cookie, err := r.Cookie("SID"); err != nil || sid == "" || cookie.Value != sid {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte("Ok."))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, logins, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		sid = ""
+	}
+}
+
+func TestQBittorrentLogin(t *testing.T) {
+	server, logins, expire := newQBittorrentServer(t)
+	logger := zerolog.Nop()
+	client := NewQBittorrent(newTestDownloadConfig(server.URL+"/"), &logger)
+
+	for i := 0; i < 2; i++ {
+		hash, err := client.AddTorrent(context.Background(), testMagnet, models.AddTorrentOptions{})
+		if err != nil {
+			t.Fatalf("AddTorrent() error = %v", err)
+		}
+		if hash != "c12fe1c06bba254a9dc9f519b335aa7c1367a88a" {
+			t.Errorf("AddTorrent() hash = %s", hash)
+		}
+	}
+	if *logins != 1 {
+		t.Errorf("logins = %d, want 1", *logins)
+	}
+
+	expire()
+	if _, err := client.AddTorrent(context.Background(), testMagnet, models.AddTorrentOptions{}); err != nil {
+		t.Fatalf("AddTorrent() after the session expired error = %v", err)
+	}
+	if *logins != 2 {
+		t.Errorf("logins = %d, want 2", *logins)
+	}
+}
+
+func TestQBittorrentInvalidCredentials(t *testing.T) {
+	server, _, _ := newQBittorrentServer(t)
+	logger := zerolog.Nop()
+	cfg := newTestDownloadConfig(server.URL)
+	cfg.DownloadClient.Password = "wrong"
+	client := NewQBittorrent(cfg, &logger)
+
+	if _, err := client.AddTorrent(context.Background(), testMagnet, models.AddTorrentOptions{}); !errors.Is(err, ErrBadResponse) {
+		t.Errorf("AddTorrent() error = %v, want ErrBadResponse", err)
+	}
+}
+
+func TestQBittorrentAddTorrentRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		existing string
+		want     error
+	}{
+		{"added", http.StatusOK, "Ok.", "", nil},
+		{"fails", http.StatusOK, "Fails.", "", ErrBadResponse},
+		{"fails duplicate", http.StatusOK, "Fails.", `[{"hash":"c12fe1c06bba254a9dc9f519b335aa7c1367a88a"}]`, ErrDuplicateTorrent},
+		{"conflict", http.StatusConflict, "Torrent already exists", "", ErrBadResponse},
+		{"conflict duplicate", http.StatusConflict, "Torrent already exists", `[{"hash":"c12fe1c06bba254a9dc9f519b335aa7c1367a88a"}]`, ErrDuplicateTorrent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/v2/torrents/add":
+					w.WriteHeader(tt.status)
+					w.Write([]byte(tt.body))
+				case "/api/v2/torrents/info":
+					if tt.existing == "" {
+						w.Write([]byte("[]"))
+						return
+					}
+					w.Write([]byte(tt.existing))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+			logger := zerolog.Nop()
+			cfg := newTestDownloadConfig(server.URL)
+			cfg.DownloadClient = config.DownloadClient{}
+			client := NewQBittorrent(cfg, &logger)
+
+			hash, err := client.AddTorrent(context.Background(), testMagnet, models.AddTorrentOptions{})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("AddTorrent() error = %v, want %v", err, tt.want)
+			}
+			wantHash := "c12fe1c06bba254a9dc9f519b335aa7c1367a88a"
+			if tt.want == ErrBadResponse {
+				wantHash = ""
+			}
+			if hash != wantHash {
+				t.Errorf("AddTorrent() hash = %q, want %q", hash, wantHash)
+			}
+		})
+	}
+}
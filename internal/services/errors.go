@@ -0,0 +1,85 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+var (
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+	ErrRateLimited         = errors.New("upstream rate limited")
+	ErrBadResponse         = errors.New("bad upstream response")
+	ErrNotFound            = errors.New("upstream resource not found")
+	// ErrDuplicateTorrent is returned by download clients when the torrent was already added
+	ErrDuplicateTorrent = errors.New("torrent already exists in download client")
+)
+
+const bodySnippetSize = 256
+
+// UpstreamError describes a failed upstream call, Kind is one of the Err* values so it can be
+// matched with errors.Is, StatusCode is zero when no response was received.
+type UpstreamError struct {
+	Kind       error
+	Upstream   string
+	StatusCode int
+	Body       string
+	Err        error
+}
+
+func (e *UpstreamError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s: %v", e.Upstream, e.Kind))
+	if e.StatusCode != 0 {
+		sb.WriteString(fmt.Sprintf(" (status %d)", e.StatusCode))
+	}
+	if e.Err != nil {
+		sb.WriteString(fmt.Sprintf(": %v", e.Err))
+	}
+	if e.Body != "" {
+		sb.WriteString(fmt.Sprintf(", body: %s", e.Body))
+	}
+	return sb.String()
+}
+
+func (e *UpstreamError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// newUpstreamError builds the error for a failed call, res may be nil when no response was received.
+func newUpstreamError(upstream string, res *resty.Response, err error) *UpstreamError {
+	e := &UpstreamError{Kind: ErrUpstreamUnavailable, Upstream: upstream, Err: err}
+	if res == nil || res.RawResponse == nil {
+		return e
+	}
+	e.StatusCode = res.StatusCode()
+	e.Body = snippet(res.Body())
+	switch {
+	case err != nil:
+		e.Kind = ErrBadResponse
+	case e.StatusCode == http.StatusNotFound:
+		e.Kind = ErrNotFound
+	case e.StatusCode == http.StatusTooManyRequests:
+		e.Kind = ErrRateLimited
+	case e.StatusCode >= http.StatusInternalServerError:
+		e.Kind = ErrUpstreamUnavailable
+	default:
+		e.Kind = ErrBadResponse
+	}
+	return e
+}
+
+func snippet(body []byte) string {
+	s := strings.TrimSpace(string(body))
+	if len(s) > bodySnippetSize {
+		return s[:bodySnippetSize] + "..."
+	}
+	return s
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/xochilpili/processor-films/internal/magnet"
+	"github.com/xochilpili/processor-films/internal/metainfo"
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+// localTorrentMetadata builds the metadata in process, the file list comes from the .torrent file
+// when one is available. Otherwise the auto source asks torrent-metadata-api and the local source
+// only knows the magnet fields, so the metadata has no files.
+func (a *Api) localTorrentMetadata(ctx context.Context, torrent *models.Torrent) (*models.TorrentMetadata, error) {
+	m, err := magnet.Parse(torrent.Magnet)
+	if err != nil {
+		return nil, &UpstreamError{Kind: ErrBadResponse, Upstream: TORRENT_FILE, Err: err}
+	}
+	for _, source := range torrentSources(torrent) {
+		metadata, err := a.GetTorrentFile(ctx, source)
+		if err != nil {
+			a.logger.Err(err).Msgf("error while fetching torrent file for %s from %s", torrent.Title, source)
+			continue
+		}
+		if metadata.Data.InfoHash != m.InfoHash {
+			a.logger.Warn().Msgf("torrent file from %s does not match the infohash of %s", source, torrent.Title)
+			continue
+		}
+		return metadata, nil
+	}
+	if a.config.MetadataSource == "auto" {
+		a.logger.Warn().Msgf("no torrent file available for %s, falling back to %s", torrent.Title, TORRENT_METADATA_API)
+		return a.remoteTorrentMetadata(ctx, torrent)
+	}
+	a.logger.Info().Msgf("no torrent file available for %s, using magnet metadata", torrent.Title)
+	return metainfo.FromMagnet(m, torrent.Magnet), nil
+}
+
+// GetTorrentFile downloads and parses a .torrent file, data urls holding the raw torrent
+// (e.g. data:application/x-bittorrent;base64,ZDg6...) are parsed without any request.
+func (a *Api) GetTorrentFile(ctx context.Context, url string) (*models.TorrentMetadata, error) {
+	if strings.HasPrefix(url, "data:") {
+		data, err := decodeDataUrl(url)
+		if err == nil {
+			var metadata *models.TorrentMetadata
+			if metadata, err = metainfo.Parse(data); err == nil {
+				return metadata, nil
+			}
+		}
+		return nil, &UpstreamError{Kind: ErrBadResponse, Upstream: TORRENT_FILE, Err: err}
+	}
+	res, err := a.do(ctx, TORRENT_FILE, func(r *resty.Request) (*resty.Response, error) {
+		return r.Get(url)
+	})
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := metainfo.Parse(res.Body())
+	if err != nil {
+		return nil, newUpstreamError(TORRENT_FILE, res, err)
+	}
+	return metadata, nil
+}
+
+// decodeDataUrl returns the payload of a data url, base64 or percent encoded.
+func decodeDataUrl(dataUrl string) ([]byte, error) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(dataUrl, "data:"), ",")
+	if !ok {
+		return nil, errors.New("malformed data url")
+	}
+	if strings.HasSuffix(header, ";base64") {
+		return base64.StdEncoding.DecodeString(payload)
+	}
+	data, err := url.PathUnescape(payload)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(data), nil
+}
+
+// torrentSources returns the .torrent file urls of the torrent, the provider url first and then
+// the magnet exact and acceptable sources.
+func torrentSources(torrent *models.Torrent) []string {
+	var sources []string
+	if torrent.TorrentUrl != "" {
+		sources = append(sources, torrent.TorrentUrl)
+	}
+	if m, err := magnet.Parse(torrent.Magnet); err == nil {
+		sources = append(sources, m.Sources...)
+	}
+	return sources
+}
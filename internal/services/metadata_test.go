@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/processor-films/internal/config"
+	"github.com/xochilpili/processor-films/internal/metainfo"
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+var torrentFile = []byte("d8:announce21:udp://tracker.test:804:infod5:filesld6:lengthi1024e4:pathl9:Movie.mkveed6:lengthi64e4:pathl4:Subs13:2_Spanish.srteee4:name10:Movie.2024" +
+	"12:piece lengthi16384e6:pieces0:ee")
+
+func newTestApi(source string, metadataApiUrl string) *Api {
+	logger := zerolog.Nop()
+	upstream := config.Upstream{Concurrency: 1, Timeout: time.Second}
+	return NewApi(&config.Config{
+		MetadataSource:        source,
+		TorrentMetadataApiUrl: metadataApiUrl,
+		TorrentMetadataApi:    upstream,
+		TorrentFile:           upstream,
+	}, &logger)
+}
+
+func newTorrentServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/Movie.2024.torrent" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-bittorrent")
+		w.Write(torrentFile)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGetTorrentFile(t *testing.T) {
+	server := newTorrentServer(t)
+	api := newTestApi("local", "")
+
+	metadata, err := api.GetTorrentFile(context.Background(), server.URL+"/Movie.2024.torrent")
+	if err != nil {
+		t.Fatalf("GetTorrentFile() error = %v", err)
+	}
+	if metadata.Data.Name != "Movie.2024" || len(metadata.Data.Files) != 2 {
+		t.Fatalf("GetTorrentFile() = %+v", metadata.Data)
+	}
+	if metadata.Data.Files[1].Path != "Movie.2024/Subs/2_Spanish.srt" {
+		t.Errorf("file path = %s", metadata.Data.Files[1].Path)
+	}
+
+	_, err = api.GetTorrentFile(context.Background(), server.URL+"/missing.torrent")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetTorrentFile() of a missing file error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalTorrentMetadataFromTorrentUrl(t *testing.T) {
+	server := newTorrentServer(t)
+	expected, err := metainfo.Parse(torrentFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	torrent := &models.Torrent{
+		Title:      "Movie 2024",
+		Magnet:     "magnet:?xt=urn:btih:" + expected.Data.InfoHash + "&dn=Movie.2024",
+		TorrentUrl: server.URL + "/Movie.2024.torrent",
+	}
+
+	metadata, err := newTestApi("local", "").GetTorrentMetadata(context.Background(), torrent)
+	if err != nil {
+		t.Fatalf("GetTorrentMetadata() error = %v", err)
+	}
+	if len(metadata.Data.Files) != 2 {
+		t.Errorf("GetTorrentMetadata() files = %+v, want the .torrent files", metadata.Data.Files)
+	}
+}
+
+func TestAutoTorrentMetadataFallsBackToApi(t *testing.T) {
+	server := newTorrentServer(t)
+	metadataApi := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"name":"Movie.2024","files":[{"name":"Movie.mkv","path":"Movie.2024/Movie.mkv"},{"name":"Movie.es.srt","path":"Movie.2024/Movie.es.srt"}]}}`))
+	}))
+	t.Cleanup(metadataApi.Close)
+	torrent := &models.Torrent{
+		Title:      "Movie 2024",
+		Magnet:     "magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a&dn=Movie.2024",
+		TorrentUrl: server.URL + "/missing.torrent",
+	}
+
+	metadata, err := newTestApi("auto", metadataApi.URL).GetTorrentMetadata(context.Background(), torrent)
+	if err != nil {
+		t.Fatalf("GetTorrentMetadata() error = %v", err)
+	}
+	if len(metadata.Data.Files) != 2 {
+		t.Errorf("GetTorrentMetadata() files = %+v, want the torrent-metadata-api files", metadata.Data.Files)
+	}
+
+	metadata, err = newTestApi("local", metadataApi.URL).GetTorrentMetadata(context.Background(), torrent)
+	if err != nil {
+		t.Fatalf("GetTorrentMetadata() error = %v", err)
+	}
+	if len(metadata.Data.Files) != 0 || metadata.Data.InfoHash != "c12fe1c06bba254a9dc9f519b335aa7c1367a88a" {
+		t.Errorf("GetTorrentMetadata() = %+v, want the magnet metadata", metadata.Data)
+	}
+}
+
+func TestGetTorrentFileFromDataUrl(t *testing.T) {
+	expected, err := metainfo.Parse(torrentFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		url  string
+		err  error
+	}{
+		{"base64", "data:application/x-bittorrent;base64," + base64.StdEncoding.EncodeToString(torrentFile), nil},
+		{"percent encoded", "data:application/x-bittorrent," + url.PathEscape(string(torrentFile)), nil},
+		{"invalid base64", "data:application/x-bittorrent;base64,not base64", ErrBadResponse},
+		{"not a torrent", "data:text/plain,hello", ErrBadResponse},
+		{"malformed", "data:application/x-bittorrent", ErrBadResponse},
+	}
+	// no upstream is configured, data urls must not be requested
+	api := newTestApi("local", "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := api.GetTorrentFile(context.Background(), tt.url)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("GetTorrentFile() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && metadata.Data.InfoHash != expected.Data.InfoHash {
+				t.Errorf("GetTorrentFile() infohash = %s, want %s", metadata.Data.InfoHash, expected.Data.InfoHash)
+			}
+		})
+	}
+}
+
+func TestLocalTorrentMetadataFromDataUrl(t *testing.T) {
+	expected, err := metainfo.Parse(torrentFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	torrent := &models.Torrent{
+		Title:      "Movie 2024",
+		Magnet:     "magnet:?xt=urn:btih:" + expected.Data.InfoHash + "&dn=Movie.2024",
+		TorrentUrl: "data:application/x-bittorrent;base64," + base64.StdEncoding.EncodeToString(torrentFile),
+	}
+
+	metadata, err := newTestApi("local", "").GetTorrentMetadata(context.Background(), torrent)
+	if err != nil {
+		t.Fatalf("GetTorrentMetadata() error = %v", err)
+	}
+	if len(metadata.Data.Files) != 2 {
+		t.Errorf("GetTorrentMetadata() files = %+v", metadata.Data.Files)
+	}
+}
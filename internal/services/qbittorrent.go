@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/processor-films/internal/config"
+	"github.com/xochilpili/processor-films/internal/magnet"
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+const QBITTORRENT = "qbittorrent"
+
+// QBittorrent talks to the qBittorrent Web API, the session cookie is kept by the resty cookie jar.
+type QBittorrent struct {
+	config   *config.Config
+	logger   *zerolog.Logger
+	upstream *upstream
+	mu       sync.Mutex
+	loggedIn bool
+}
+
+func NewQBittorrent(config *config.Config, logger *zerolog.Logger) *QBittorrent {
+	return &QBittorrent{
+		config: config,
+		logger: logger,
+		// no retries, see DownloadClient.AddTorrent
+		upstream: newUpstream(DOWNLOAD_CLIENT, config.TransmissionApi, 0, config.Debug, logger),
+	}
+}
+
+func (q *QBittorrent) ResetBreakers() {
+	q.upstream.breaker.Reset()
+}
+
+// AddTorrent reads the infohash from the magnet since qBittorrent only answers "Ok." or "Fails.".
+func (q *QBittorrent) AddTorrent(ctx context.Context, magnetLink string, opts models.AddTorrentOptions) (string, error) {
+	hash, err := magnet.InfoHash(magnetLink)
+	if err != nil {
+		return "", err
+	}
+
+	url := q.url("/api/v2/torrents/add")
+	form := map[string]string{
+		"urls": magnetLink,
+		// qBittorrent 5 renamed paused to stopped
+		"paused":             strconv.FormatBool(opts.Paused),
+		"stopped":            strconv.FormatBool(opts.Paused),
+		"sequentialDownload": strconv.FormatBool(opts.Sequential),
+	}
+	if opts.SavePath != "" {
+		form["savepath"] = opts.SavePath
+	}
+	if opts.Category != "" {
+		form["category"] = opts.Category
+	}
+	if len(opts.Tags) > 0 {
+		form["tags"] = strings.Join(opts.Tags, ",")
+	}
+
+	res, err := q.request(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.SetFormData(form).Post(url)
+	})
+	// newer versions answer 409 when the torrent could not be added
+	rejected := res != nil && (res.StatusCode() == http.StatusConflict || strings.TrimSpace(res.String()) == "Fails.")
+	if err != nil && !rejected {
+		q.logger.Err(err).Msg("error while adding new torrent from magnet")
+		return "", err
+	}
+	if rejected {
+		// qBittorrent does not tell why it failed, duplicates are the usual reason
+		torrents, err := q.Torrents(ctx, []string{hash})
+		if err != nil {
+			return "", err
+		}
+		if len(torrents) > 0 {
+			q.logger.Warn().Msgf("torrent %s already exists in qbittorrent", hash)
+			return hash, ErrDuplicateTorrent
+		}
+		err = newUpstreamError(DOWNLOAD_CLIENT, res, errors.New("qbittorrent rejected the torrent"))
+		q.logger.Err(err).Msg("error while adding new torrent from magnet")
+		return "", err
+	}
+	return hash, nil
+}
+
+// Torrents returns the torrents info for the given hashes.
+func (q *QBittorrent) Torrents(ctx context.Context, hashes []string) ([]models.BitTorrent, error) {
+	var result []models.BitTorrent
+	url := q.url("/api/v2/torrents/info")
+	res, err := q.request(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.SetQueryParam("hashes", strings.Join(hashes, "|")).Get(url)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decode(DOWNLOAD_CLIENT, res, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// url returns the Web API endpoint url, the configured url may end with a slash.
+func (q *QBittorrent) url(endpoint string) string {
+	return strings.TrimSuffix(q.config.TransmissionApiUrl, "/") + endpoint
+}
+
+// request sends the call once logged in, when the session expired it logs in again and retries once.
+func (q *QBittorrent) request(ctx context.Context, call func(r *resty.Request) (*resty.Response, error)) (*resty.Response, error) {
+	if err := q.login(ctx, false); err != nil {
+		return nil, err
+	}
+	res, err := q.upstream.do(ctx, call)
+	if res != nil && res.StatusCode() == http.StatusForbidden {
+		if err := q.login(ctx, true); err != nil {
+			return nil, err
+		}
+		res, err = q.upstream.do(ctx, call)
+	}
+	return res, err
+}
+
+// login authenticates against the Web API, nothing is done when no username is configured
+// (e.g. clients bypassing authentication for the local network) or when already logged in.
+func (q *QBittorrent) login(ctx context.Context, force bool) error {
+	if q.config.DownloadClient.Username == "" {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.loggedIn && !force {
+		return nil
+	}
+
+	url := q.url("/api/v2/auth/login")
+	res, err := q.upstream.do(ctx, func(r *resty.Request) (*resty.Response, error) {
+		return r.SetFormData(map[string]string{
+			"username": q.config.DownloadClient.Username,
+			"password": q.config.DownloadClient.Password,
+		}).Post(url)
+	})
+	if err != nil {
+		q.logger.Err(err).Msg("error while login into qbittorrent")
+		return err
+	}
+	if strings.TrimSpace(res.String()) != "Ok." {
+		q.loggedIn = false
+		return newUpstreamError(DOWNLOAD_CLIENT, res, errors.New("invalid qbittorrent credentials"))
+	}
+	q.loggedIn = true
+	return nil
+}
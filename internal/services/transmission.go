@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/processor-films/internal/config"
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+const (
+	TRANSMISSION            = "transmission"
+	transmissionSessionId   = "X-Transmission-Session-Id"
+	transmissionRpcPath     = "/transmission/rpc"
+	transmissionRpcAttempts = 2
+)
+
+type transmissionRequest struct {
+	Method    string `json:"method"`
+	Arguments any    `json:"arguments,omitempty"`
+}
+
+type transmissionResponse struct {
+	Result    string          `json:"result"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// Transmission talks to the Transmission RPC api negotiating the X-Transmission-Session-Id header.
+type Transmission struct {
+	config    *config.Config
+	logger    *zerolog.Logger
+	upstream  *upstream
+	mu        sync.Mutex
+	sessionId string
+}
+
+func NewTransmission(config *config.Config, logger *zerolog.Logger) *Transmission {
+	return &Transmission{
+		config: config,
+		logger: logger,
+		// no retries, see DownloadClient.AddTorrent
+		upstream: newUpstream(DOWNLOAD_CLIENT, config.TransmissionApi, 0, config.Debug, logger),
+	}
+}
+
+func (t *Transmission) ResetBreakers() {
+	t.upstream.breaker.Reset()
+}
+
+type transmissionTorrentAdded struct {
+	Id         int    `json:"id"`
+	Name       string `json:"name"`
+	HashString string `json:"hashString"`
+}
+
+type transmissionAddResponse struct {
+	TorrentAdded     *transmissionTorrentAdded `json:"torrent-added"`
+	TorrentDuplicate *transmissionTorrentAdded `json:"torrent-duplicate"`
+}
+
+// AddTorrent returns the infohash from torrent-added, or from torrent-duplicate for known torrents.
+func (t *Transmission) AddTorrent(ctx context.Context, magnetLink string, opts models.AddTorrentOptions) (string, error) {
+	args := map[string]any{
+		"filename": magnetLink,
+		"paused":   opts.Paused,
+	}
+	if opts.SavePath != "" {
+		args["download-dir"] = opts.SavePath
+	}
+	// transmission has no categories, category and tags are sent as labels
+	var labels []string
+	if opts.Category != "" {
+		labels = append(labels, opts.Category)
+	}
+	labels = append(labels, opts.Tags...)
+	if len(labels) > 0 {
+		args["labels"] = labels
+	}
+	if opts.Sequential {
+		// supported since transmission 4.1, older versions ignore it
+		args["sequential_download"] = true
+	}
+	var result transmissionAddResponse
+	err := t.rpc(ctx, "torrent-add", args, &result)
+	if err != nil {
+		t.logger.Err(err).Msg("error while adding new torrent from magnet")
+		return "", err
+	}
+	if result.TorrentDuplicate != nil {
+		t.logger.Warn().Msgf("torrent %s already exists in transmission", result.TorrentDuplicate.HashString)
+		return strings.ToLower(result.TorrentDuplicate.HashString), ErrDuplicateTorrent
+	}
+	if result.TorrentAdded == nil {
+		return "", newUpstreamError(DOWNLOAD_CLIENT, nil, errors.New("transmission did not return the added torrent"))
+	}
+	return strings.ToLower(result.TorrentAdded.HashString), nil
+}
+
+// rpc calls the method and decodes the response arguments into out, when transmission answers
+// 409 the new session id is stored and the call is sent again.
+func (t *Transmission) rpc(ctx context.Context, method string, args any, out any) error {
+	url := strings.TrimSuffix(strings.TrimSuffix(t.config.TransmissionApiUrl, "/"), transmissionRpcPath) + transmissionRpcPath
+	body := transmissionRequest{Method: method, Arguments: args}
+
+	for attempt := 0; attempt < transmissionRpcAttempts; attempt++ {
+		t.mu.Lock()
+		sessionId := t.sessionId
+		t.mu.Unlock()
+
+		res, err := t.upstream.do(ctx, func(r *resty.Request) (*resty.Response, error) {
+			r.SetHeader("Content-Type", "application/json").
+				SetHeader(transmissionSessionId, sessionId).
+				SetBody(body)
+			if t.config.DownloadClient.Username != "" {
+				r.SetBasicAuth(t.config.DownloadClient.Username, t.config.DownloadClient.Password)
+			}
+			return r.Post(url)
+		})
+		if res != nil && res.StatusCode() == http.StatusConflict {
+			t.mu.Lock()
+			t.sessionId = res.Header().Get(transmissionSessionId)
+			t.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		var result transmissionResponse
+		if err := decode(DOWNLOAD_CLIENT, res, &result); err != nil {
+			return err
+		}
+		if result.Result != "success" {
+			return newUpstreamError(DOWNLOAD_CLIENT, res, fmt.Errorf("transmission %s failed: %s", method, result.Result))
+		}
+		if out != nil {
+			if err := json.Unmarshal(result.Arguments, out); err != nil {
+				return newUpstreamError(DOWNLOAD_CLIENT, res, err)
+			}
+		}
+		return nil
+	}
+	return newUpstreamError(DOWNLOAD_CLIENT, nil, errors.New("unable to negotiate transmission session id"))
+}
+
+type transmissionTorrent struct {
+	HashString    string   `json:"hashString"`
+	Name          string   `json:"name"`
+	Status        int      `json:"status"`
+	Error         int      `json:"error"`
+	PercentDone   float64  `json:"percentDone"`
+	UploadRatio   float64  `json:"uploadRatio"`
+	TotalSize     int64    `json:"totalSize"`
+	LeftUntilDone int64    `json:"leftUntilDone"`
+	DownloadDir   string   `json:"downloadDir"`
+	AddedDate     int64    `json:"addedDate"`
+	DoneDate      int64    `json:"doneDate"`
+	RateDownload  int64    `json:"rateDownload"`
+	RateUpload    int64    `json:"rateUpload"`
+	Eta           int64    `json:"eta"`
+	Labels        []string `json:"labels"`
+}
+
+var transmissionFields = []string{"hashString", "name", "status", "error", "percentDone", "uploadRatio", "totalSize", "leftUntilDone", "downloadDir", "addedDate", "doneDate", "rateDownload", "rateUpload", "eta", "labels"}
+
+// Torrents returns the torrents info for the given hashes mapped to the qBittorrent model.
+func (t *Transmission) Torrents(ctx context.Context, hashes []string) ([]models.BitTorrent, error) {
+	var result struct {
+		Torrents []transmissionTorrent `json:"torrents"`
+	}
+	err := t.rpc(ctx, "torrent-get", map[string]any{"ids": hashes, "fields": transmissionFields}, &result)
+	if err != nil {
+		return nil, err
+	}
+	torrents := make([]models.BitTorrent, 0, len(result.Torrents))
+	for _, torrent := range result.Torrents {
+		torrents = append(torrents, models.BitTorrent{
+			Hash:         strings.ToLower(torrent.HashString),
+			Name:         torrent.Name,
+			State:        transmissionState(torrent),
+			Progress:     torrent.PercentDone,
+			Ratio:        torrent.UploadRatio,
+			Size:         torrent.TotalSize,
+			TotalSize:    torrent.TotalSize,
+			AmountLeft:   torrent.LeftUntilDone,
+			SavePath:     torrent.DownloadDir,
+			ContentPath:  path.Join(torrent.DownloadDir, torrent.Name),
+			AddedOn:      torrent.AddedDate,
+			CompletionOn: torrent.DoneDate,
+			Dlspeed:      torrent.RateDownload,
+			Upspeed:      torrent.RateUpload,
+			Eta:          torrent.Eta,
+			Tags:         strings.Join(torrent.Labels, ","),
+		})
+	}
+	return torrents, nil
+}
+
+// transmissionState maps transmission status codes to the qBittorrent states.
+func transmissionState(torrent transmissionTorrent) string {
+	if torrent.Error != 0 {
+		return "error"
+	}
+	switch torrent.Status {
+	case 0:
+		if torrent.PercentDone >= 1 {
+			return "pausedUP"
+		}
+		return "pausedDL"
+	case 1, 2:
+		return "checkingDL"
+	case 3:
+		return "queuedDL"
+	case 4:
+		return "downloading"
+	case 5:
+		return "queuedUP"
+	case 6:
+		return "uploading"
+	}
+	return "unknown"
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/processor-films/internal/breaker"
+	"github.com/xochilpili/processor-films/internal/config"
+	"github.com/xochilpili/processor-films/internal/ratelimit"
+)
+
+// upstream wraps the http client of an external service with its rate limits and circuit breaker.
+type upstream struct {
+	name    string
+	debug   bool
+	logger  *zerolog.Logger
+	client  *resty.Client
+	limiter *ratelimit.Limiter
+	breaker *breaker.Breaker
+}
+
+func newUpstream(name string, cfg config.Upstream, retries int, debug bool, logger *zerolog.Logger) *upstream {
+	// resty backoff is exponential with jitter between RetryWait and RetryMaxWait
+	client := resty.New().
+		SetTimeout(cfg.Timeout).
+		SetRetryCount(retries).
+		SetRetryWaitTime(cfg.RetryWait).
+		SetRetryMaxWaitTime(cfg.RetryMaxWait).
+		AddRetryCondition(func(r *resty.Response, err error) bool {
+			return err != nil || isUnavailable(r.StatusCode())
+		})
+	return &upstream{
+		name:    name,
+		debug:   debug,
+		logger:  logger,
+		client:  client,
+		limiter: ratelimit.New(name, cfg.Concurrency, cfg.Rps),
+		breaker: breaker.New(name, cfg.BreakerThreshold, cfg.BreakerCooldown),
+	}
+}
+
+// do sends the request built by call to the upstream honoring its circuit breaker and rate limits,
+// failures and non-2xx responses are returned as *UpstreamError, the response is returned for the latter.
+func (u *upstream) do(ctx context.Context, call func(r *resty.Request) (*resty.Response, error)) (*resty.Response, error) {
+	if err := u.breaker.Allow(); err != nil {
+		return nil, newUpstreamError(u.name, nil, err)
+	}
+
+	release, err := u.limiter.Acquire(ctx)
+	if err != nil {
+		u.logger.Err(err).Msgf("error while waiting for %s rate limit", u.name)
+		return nil, err
+	}
+	defer release()
+
+	res, err := call(u.client.R().SetContext(ctx).SetDebug(u.debug))
+	if err != nil {
+		if ctx.Err() == nil && u.breaker.Failure() {
+			u.logger.Warn().Msgf("circuit breaker opened for %s", u.name)
+		}
+		return nil, newUpstreamError(u.name, nil, err)
+	}
+	if isUnavailable(res.StatusCode()) {
+		if u.breaker.Failure() {
+			u.logger.Warn().Msgf("circuit breaker opened for %s", u.name)
+		}
+	} else {
+		u.breaker.Success()
+	}
+	if res.IsError() {
+		return res, newUpstreamError(u.name, res, nil)
+	}
+	return res, nil
+}
+
+// decode unmarshals the json response body, undecodable payloads are returned as ErrBadResponse.
+func decode(upstream string, res *resty.Response, v any) error {
+	if err := json.Unmarshal(res.Body(), v); err != nil {
+		return newUpstreamError(upstream, res, err)
+	}
+	return nil
+}
+
+func isUnavailable(status int) bool {
+	return status >= http.StatusInternalServerError || status == http.StatusTooManyRequests
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/processor-films/internal/breaker"
+	"github.com/xochilpili/processor-films/internal/config"
+)
+
+// newStatusServer answers each request with the next status of statuses, the last one is repeated.
+func newStatusServer(t *testing.T, hits *int32, statuses ...int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(atomic.AddInt32(hits, 1)) - 1
+		if i >= len(statuses) {
+			i = len(statuses) - 1
+		}
+		w.WriteHeader(statuses[i])
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func get(u *upstream, url string) error {
+	_, err := u.do(context.Background(), func(r *resty.Request) (*resty.Response, error) {
+		return r.Get(url)
+	})
+	return err
+}
+
+func TestUpstreamBreaker(t *testing.T) {
+	var hits int32
+	server := newStatusServer(t, &hits, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK)
+	logger := zerolog.Nop()
+	u := newUpstream("test", config.Upstream{Concurrency: 1, Timeout: time.Second, BreakerThreshold: 2, BreakerCooldown: 50 * time.Millisecond}, 0, false, &logger)
+
+	for i := 0; i < 2; i++ {
+		if err := get(u, server.URL); !errors.Is(err, ErrUpstreamUnavailable) {
+			t.Fatalf("call %d error = %v, want ErrUpstreamUnavailable", i+1, err)
+		}
+	}
+	// open, the upstream is not called
+	if err := get(u, server.URL); !errors.Is(err, breaker.ErrOpen) {
+		t.Fatalf("open breaker error = %v, want ErrOpen", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Fatalf("upstream calls = %d, want 2", n)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if err := get(u, server.URL); err != nil {
+		t.Fatalf("probe error = %v", err)
+	}
+	if err := get(u, server.URL); err != nil {
+		t.Fatalf("closed breaker error = %v", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 4 {
+		t.Errorf("upstream calls = %d, want 4", n)
+	}
+}
+
+func TestUpstreamRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		want     error
+		hits     int32
+	}{
+		{"unavailable and rate limited", []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK}, nil, 3},
+		{"exhausted", []int{http.StatusTooManyRequests}, ErrRateLimited, 3},
+		{"not found", []int{http.StatusNotFound}, ErrNotFound, 1},
+		{"bad request", []int{http.StatusBadRequest}, ErrBadResponse, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int32
+			server := newStatusServer(t, &hits, tt.statuses...)
+			logger := zerolog.Nop()
+			cfg := config.Upstream{Concurrency: 1, Timeout: time.Second, RetryWait: time.Millisecond, RetryMaxWait: 5 * time.Millisecond}
+			u := newUpstream("test", cfg, 2, false, &logger)
+
+			err := get(u, server.URL)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("do() error = %v, want %v", err, tt.want)
+			}
+			if n := atomic.LoadInt32(&hits); n != tt.hits {
+				t.Errorf("upstream calls = %d, want %d", n, tt.hits)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package subtitles
+
+import (
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+// LANGUAGE_UNKNOWN is reported for subtitle files whose language could not be detected.
+const LANGUAGE_UNKNOWN = "und"
+
+// trackExtensions are the subtitle formats found in torrents, .idx/.sub pairs are vobsub images.
+var trackExtensions = []string{".srt", ".ass", ".ssa", ".sub", ".idx", ".vtt"}
+
+// languageNames maps the ISO 639-1/639-2 codes and the names used by release groups to
+// language tags, regional tags are used when the name implies the variant.
+var languageNames = map[string]string{
+	"spa":             "es",
+	"esp":             "es",
+	"spanish":         "es",
+	"espanol":         "es",
+	"español":         "es",
+	"castellano":      "es-ES",
+	"castilian":       "es-ES",
+	"latino":          "es-419",
+	"latam":           "es-419",
+	"latinoamerica":   "es-419",
+	"latinoamericano": "es-419",
+	"latin":           "es-419",
+	"eng":             "en",
+	"english":         "en",
+	"fre":             "fr",
+	"fra":             "fr",
+	"french":          "fr",
+	"por":             "pt",
+	"portuguese":      "pt",
+	"brazilian":       "pt-BR",
+	"ita":             "it",
+	"italian":         "it",
+	"ger":             "de",
+	"deu":             "de",
+	"german":          "de",
+	"jpn":             "ja",
+	"japanese":        "ja",
+	"kor":             "ko",
+	"korean":          "ko",
+	"chi":             "zh",
+	"zho":             "zh",
+	"chinese":         "zh",
+	"rus":             "ru",
+	"russian":         "ru",
+}
+
+// languageCodes are ISO 639-1 codes, they are short enough to appear in titles ("It", "Es") so
+// they are only trusted at the end of a file name or as a whole folder name.
+var languageCodes = map[string]string{
+	"es": "es",
+	"en": "en",
+	"fr": "fr",
+	"pt": "pt",
+	"it": "it",
+	"de": "de",
+	"ja": "ja",
+	"ko": "ko",
+	"zh": "zh",
+	"ru": "ru",
+}
+
+// regions maps the region following a language code (es-MX, pt_BR) to the regional tag.
+var regions = map[string]map[string]string{
+	"es": {"mx": "es-419", "la": "es-419", "419": "es-419", "ar": "es-419", "co": "es-419", "cl": "es-419", "lat": "es-419", "es": "es-ES", "sp": "es-ES"},
+	"pt": {"br": "pt-BR", "pt": "pt-PT"},
+}
+
+var forcedTokens = []string{"forced", "foreign"}
+var sdhTokens = []string{"sdh", "cc", "hi"}
+
+// ignoredTokens are trailing tokens which say nothing about the language, e.g. Movie.spa.default.srt.
+var ignoredTokens = []string{"default", "full", "sub", "subs", "subtitle", "subtitles"}
+
+var subtitleFolders = []string{"sub", "subs", "subtitle", "subtitles", "subtitulos", "subtítulos"}
+
+// Track is a subtitle file found in a torrent.
+type Track struct {
+	File     string `json:"file"`
+	Language string `json:"language"`
+	Format   string `json:"format"`
+	Forced   bool   `json:"forced,omitempty"`
+	SDH      bool   `json:"sdh,omitempty"`
+}
+
+// Languages is the set of language tags of the subtitles of a torrent.
+type Languages map[string]bool
+
+// Has returns true when the set includes the language, a tag without region (es) also
+// matches its regional variants (es-419, es-ES).
+func (l Languages) Has(language string) bool {
+	language = strings.ToLower(language)
+	for tag := range l {
+		tag = strings.ToLower(tag)
+		if tag == language || (!strings.Contains(language, "-") && baseLanguage(tag) == language) {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectTracks returns the subtitle tracks found in the torrent files.
+func DetectTracks(files []models.MetadataFile) []Track {
+	var tracks []Track
+	for _, file := range files {
+		if track, ok := Detect(file); ok {
+			tracks = append(tracks, track)
+		}
+	}
+	return tracks
+}
+
+// DetectLanguages returns the set of languages of the subtitle tracks found in the torrent files.
+func DetectLanguages(files []models.MetadataFile) Languages {
+	return TrackLanguages(DetectTracks(files))
+}
+
+// TrackLanguages returns the set of languages of the tracks, forced tracks only translate foreign
+// dialogue so they do not cover a language.
+func TrackLanguages(tracks []Track) Languages {
+	languages := Languages{}
+	for _, track := range tracks {
+		if track.Forced {
+			continue
+		}
+		languages[track.Language] = true
+	}
+	return languages
+}
+
+// Detect returns the subtitle track of the file, false when the file is not a subtitle.
+// The language is taken from the file name first and then from its folders, e.g.
+// Movie.2024.es-MX.forced.srt, Subs/Spanish.srt or Subs/spa/2_Spanish.SDH.srt.
+func Detect(file models.MetadataFile) (Track, bool) {
+	filePath := file.Path
+	if filePath == "" {
+		filePath = file.Name
+	}
+	filePath = strings.ReplaceAll(filePath, "\\", "/")
+	name := path.Base(filePath)
+	if file.Name != "" {
+		name = path.Base(strings.ReplaceAll(file.Name, "\\", "/"))
+	}
+	ext := strings.ToLower(path.Ext(name))
+	if !hasExtension(ext, trackExtensions) {
+		return Track{}, false
+	}
+
+	track := Track{File: filePath, Format: strings.TrimPrefix(ext, ".")}
+	tokens := tokenize(strings.TrimSuffix(name, path.Ext(name)))
+	for _, token := range tokens {
+		track.Forced = track.Forced || contains(forcedTokens, token)
+		track.SDH = track.SDH || contains(sdhTokens, token)
+	}
+	track.Language = nameLanguage(tokens)
+
+	dir := path.Dir(filePath)
+	inFolder := false
+	for dir != "." && dir != "/" && dir != "" {
+		folder := strings.ToLower(path.Base(dir))
+		if contains(subtitleFolders, folder) {
+			inFolder = true
+		}
+		if track.Language == "" {
+			track.Language = folderLanguage(folder)
+		}
+		dir = path.Dir(dir)
+	}
+	if track.Language == "" {
+		// .sub files outside a subtitles folder are also used for other content, e.g. vobsub pairs
+		// with an .idx are reported by the .idx
+		if ext == ".sub" && !inFolder {
+			return Track{}, false
+		}
+		track.Language = LANGUAGE_UNKNOWN
+	}
+	return track, true
+}
+
+// nameLanguage looks for a language at the end of the file name, where release groups put it,
+// title words such as The.Italian.Job are not taken as languages.
+func nameLanguage(tokens []string) string {
+	end := trailing(tokens)
+	if end >= 2 {
+		if regional, ok := regions[languageCode(tokens[end-2])][tokens[end-1]]; ok {
+			return regional
+		}
+	}
+	if end >= 1 {
+		return tokenLanguage(tokens[end-1])
+	}
+	return ""
+}
+
+// folderLanguage accepts codes only when they are the whole folder name, e.g. Subs/es, and names
+// only at its end, e.g. Subs/2_Spanish, so the folder of the release is not taken by its title.
+func folderLanguage(folder string) string {
+	tokens := tokenize(folder)
+	if len(tokens) == 1 {
+		return tokenLanguage(tokens[0])
+	}
+	if len(tokens) == 2 {
+		if regional, ok := regions[languageCode(tokens[0])][tokens[1]]; ok {
+			return regional
+		}
+	}
+	if end := trailing(tokens); end >= 1 {
+		return languageNames[tokens[end-1]]
+	}
+	return ""
+}
+
+// labelLanguage returns the language of a label sent by subtitle sites, e.g. es-MX or
+// Spanish (Latin America), which only names the language so every token is looked up.
+func labelLanguage(label string) string {
+	tokens := tokenize(label)
+	if language := nameLanguage(tokens); language != "" {
+		return language
+	}
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if language, ok := languageNames[tokens[i]]; ok {
+			return language
+		}
+	}
+	return ""
+}
+
+// trailing returns the end of the tokens without the trailing modifiers, e.g. forced or track numbers.
+func trailing(tokens []string) int {
+	end := len(tokens)
+	for end > 0 && isModifier(tokens[end-1]) {
+		end--
+	}
+	return end
+}
+
+// tokenLanguage returns the language tag of a code or name token.
+func tokenLanguage(token string) string {
+	if language, ok := languageNames[token]; ok {
+		return language
+	}
+	return languageCodes[token]
+}
+
+// languageCode returns the language of a token without region, used to read the region after it.
+func languageCode(token string) string {
+	return baseLanguage(tokenLanguage(token))
+}
+
+func isModifier(token string) bool {
+	if contains(forcedTokens, token) || contains(sdhTokens, token) || contains(ignoredTokens, token) {
+		return true
+	}
+	// track numbers, e.g. Movie.spa.2.srt, years and resolutions are longer
+	return len(token) <= 2 && strings.IndexFunc(token, func(r rune) bool { return !unicode.IsDigit(r) }) == -1
+}
+
+func baseLanguage(tag string) string {
+	base, _, _ := strings.Cut(tag, "-")
+	return base
+}
+
+func tokenize(value string) []string {
+	return strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
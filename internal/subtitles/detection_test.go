@@ -0,0 +1,79 @@
+package subtitles
+
+import (
+	"testing"
+
+	"github.com/xochilpili/processor-films/internal/models"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		path     string
+		language string
+		forced   bool
+		sdh      bool
+	}{
+		{"Movie.2024.1080p.WEBRip.x264-RARBG/Subs/2_Spanish.srt", "es", false, false},
+		{"Movie.2024.1080p.WEBRip.x264-RARBG/Subs/3_English.SDH.srt", "en", false, true},
+		{"Movie.2024.es-MX.forced.srt", "es-419", true, false},
+		{"Movie.2024.Spanish.Latino.srt", "es-419", false, false},
+		{"Movie.2024.Castellano.srt", "es-ES", false, false},
+		{"Movie.2024/Subs/spa/1.srt", "es", false, false},
+		{"Movie.2024/Subs/Spanish/Movie.2024.srt", "es", false, false},
+		{"Movie.2024/Subs/es/Movie.2024.srt", "es", false, false},
+		{"The.Italian.Job.2003.1080p.BluRay.x264-YTS/The.Italian.Job.2003.1080p.BluRay.x264-YTS.srt", LANGUAGE_UNKNOWN, false, false},
+		{"The.Italian.Job.2003.1080p.BluRay.x264-YTS/The.Italian.Job.2003.1080p.BluRay.x264-YTS.spa.srt", "es", false, false},
+		{"The.English.Patient.1996/Subs/Spanish.srt", "es", false, false},
+		{"The.French.Dispatch.2021/The.French.Dispatch.2021.srt", LANGUAGE_UNKNOWN, false, false},
+		{"It.2017.1080p/It.2017.1080p.srt", LANGUAGE_UNKNOWN, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			track, ok := Detect(models.MetadataFile{Path: tt.path})
+			if !ok {
+				t.Fatalf("Detect(%s) is not a subtitle", tt.path)
+			}
+			if track.Language != tt.language || track.Forced != tt.forced || track.SDH != tt.sdh {
+				t.Errorf("Detect(%s) = %s forced: %v sdh: %v, want %s forced: %v sdh: %v", tt.path, track.Language, track.Forced, track.SDH, tt.language, tt.forced, tt.sdh)
+			}
+		})
+	}
+}
+
+func TestDetectIgnoresOtherFiles(t *testing.T) {
+	for _, path := range []string{"Movie.2024.1080p.mkv", "Movie.2024/Movie.2024.sub", "Movie.2024/RARBG.txt"} {
+		if _, ok := Detect(models.MetadataFile{Path: path}); ok {
+			t.Errorf("Detect(%s) is a subtitle", path)
+		}
+	}
+}
+
+func TestTrackLanguagesSkipsForced(t *testing.T) {
+	tracks := DetectTracks([]models.MetadataFile{
+		{Path: "Movie.2024/Movie.2024.spa.forced.srt"},
+		{Path: "Movie.2024/Movie.2024.eng.srt"},
+	})
+	languages := TrackLanguages(tracks)
+	if languages.Has("es") {
+		t.Errorf("forced spanish track covers es: %v", languages)
+	}
+	if !languages.Has("en") {
+		t.Errorf("english track does not cover en: %v", languages)
+	}
+}
+
+func TestTag(t *testing.T) {
+	wanted := models.SubtitleLanguage{Code: "es", Variants: []string{"es-419", "es-ES"}}
+	tests := map[string]string{
+		"es-MX":                   "es-419",
+		"Spanish (Latin America)": "es-419",
+		"Castellano":              "es-ES",
+		"spa":                     "es",
+		"English":                 "es",
+	}
+	for label, want := range tests {
+		if got := Tag(wanted, models.Subtitle{Language: label}); got != want {
+			t.Errorf("Tag(%s) = %s, want %s", label, got, want)
+		}
+	}
+}
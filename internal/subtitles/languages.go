@@ -0,0 +1,55 @@
+package subtitles
+
+import "github.com/xochilpili/processor-films/internal/models"
+
+// Match returns how well the languages satisfy the wanted language, 0 when it is not covered,
+// 1 for its preferred variant and less for the following variants. Subtitles of the language
+// without a known variant score as the last listed variant.
+func Match(wanted models.SubtitleLanguage, languages Languages) float64 {
+	if !languages.Has(wanted.Code) {
+		return 0
+	}
+	if len(wanted.Variants) == 0 {
+		return 1
+	}
+	for i, variant := range wanted.Variants {
+		if languages.Has(variant) {
+			return 1 - 0.5*float64(i)/float64(len(wanted.Variants))
+		}
+	}
+	return 1 - 0.5*float64(len(wanted.Variants)-1)/float64(len(wanted.Variants))
+}
+
+// Covers returns true when every wanted language is in the set.
+func Covers(wanted []models.SubtitleLanguage, languages Languages) bool {
+	for _, language := range wanted {
+		if !languages.Has(language.Code) {
+			return false
+		}
+	}
+	return true
+}
+
+// Coverage returns the match of the languages against the wanted languages from 0 to 1,
+// weighted by priority so the first wanted language counts the most.
+func Coverage(wanted []models.SubtitleLanguage, languages Languages) float64 {
+	var total, weights float64
+	for i, language := range wanted {
+		weight := float64(len(wanted) - i)
+		total += weight * Match(language, languages)
+		weights += weight
+	}
+	if weights == 0 {
+		return 0
+	}
+	return total / weights
+}
+
+// Tag returns the language tag of a subtitle requested for the wanted language, the language
+// sent by subtitler (e.g. es-MX, spa or Latino) is kept when it belongs to the wanted language.
+func Tag(wanted models.SubtitleLanguage, subtitle models.Subtitle) string {
+	if language := labelLanguage(subtitle.Language); baseLanguage(language) == wanted.Code {
+		return language
+	}
+	return wanted.Code
+}
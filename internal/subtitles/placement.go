@@ -0,0 +1,149 @@
+package subtitles
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xochilpili/processor-films/internal/models"
+	"github.com/xochilpili/processor-films/internal/utils"
+)
+
+var ErrNoSubtitle = errors.New("no subtitle file found")
+var ErrNoVideo = errors.New("no video file found")
+
+var subtitleExtensions = []string{".srt", ".ass", ".ssa", ".sub", ".vtt"}
+var videoExtensions = []string{".mkv", ".mp4", ".avi", ".m4v", ".mov", ".wmv"}
+
+// Extract returns the subtitle content and its extension. Zip archives are unpacked and the
+// subtitle file of the language is returned, files whose name tells another language are skipped
+// and files without a detected language are used when none matches.
+func Extract(data []byte, filename string, language string) ([]byte, string, error) {
+	if !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		ext := strings.ToLower(filepath.Ext(filename))
+		if !hasExtension(ext, subtitleExtensions) {
+			// subtitler does not always send the filename, srt is the most common format
+			ext = ".srt"
+		}
+		return data, ext, nil
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, "", fmt.Errorf("error while reading subtitle archive: %w", err)
+	}
+	var best *zip.File
+	var bestScore int
+	for _, file := range archive.File {
+		ext := strings.ToLower(filepath.Ext(file.Name))
+		if file.FileInfo().IsDir() || !hasExtension(ext, subtitleExtensions) {
+			continue
+		}
+		if score := entryScore(file.Name, language); score > bestScore {
+			best = file
+			bestScore = score
+		}
+	}
+	if best == nil {
+		return nil, "", ErrNoSubtitle
+	}
+	rc, err := best.Open()
+	if err != nil {
+		return nil, "", err
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, "", err
+	}
+	return content, strings.ToLower(filepath.Ext(best.Name)), nil
+}
+
+// entryScore ranks an archive entry for the language, 0 when it is a subtitle of another language.
+// The exact tag ranks first, then the base language and then entries without a detected language,
+// forced subtitles rank after full ones.
+func entryScore(name string, language string) int {
+	// Detect skips .sub files outside a subtitles folder, in an archive they are subtitles too
+	track, _ := Detect(models.MetadataFile{Path: name})
+	var score int
+	switch {
+	case strings.EqualFold(track.Language, language):
+		score = 6
+	case baseLanguage(strings.ToLower(track.Language)) == baseLanguage(strings.ToLower(language)):
+		score = 4
+	case track.Language == LANGUAGE_UNKNOWN || track.Language == "":
+		score = 2
+	default:
+		return 0
+	}
+	if track.Forced {
+		score--
+	}
+	return score
+}
+
+// Place writes the subtitle next to the biggest video file under contentPath, named after
+// the video and the language, e.g. Movie.Title.2024.es.srt. It returns the written path.
+func Place(contentPath string, content []byte, ext string, lang string) (string, error) {
+	video, err := findVideo(contentPath)
+	if err != nil {
+		return "", err
+	}
+	base := strings.TrimSuffix(video, filepath.Ext(video))
+	target := fmt.Sprintf("%s.%s%s", base, lang, ext)
+	if err := os.WriteFile(target, content, 0644); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// findVideo returns the biggest video file, contentPath is either the video itself or a folder.
+func findVideo(contentPath string) (string, error) {
+	info, err := os.Stat(contentPath)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		if hasExtension(strings.ToLower(filepath.Ext(contentPath)), videoExtensions) {
+			return contentPath, nil
+		}
+		return "", ErrNoVideo
+	}
+
+	var video string
+	var size int64
+	err = filepath.WalkDir(contentPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !hasExtension(strings.ToLower(filepath.Ext(path)), videoExtensions) {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		// samples are smaller than the film itself
+		if info.Size() > size {
+			video = path
+			size = info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if video == "" {
+		return "", ErrNoVideo
+	}
+	return video, nil
+}
+
+func hasExtension(ext string, extensions []string) bool {
+	return utils.Some(extensions, func(e string) bool {
+		return e == ext
+	})
+}
@@ -0,0 +1,128 @@
+package subtitles
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		filename string
+		language string
+		content  string
+		ext      string
+		err      error
+	}{
+		{"plain", []byte("1\n00:00:01,000 --> 00:00:02,000\nHola\n"), "Movie.2024.ass", "es", "1\n00:00:01,000 --> 00:00:02,000\nHola\n", ".ass", nil},
+		{"plain without filename", []byte("Hola"), "", "es", "Hola", ".srt", nil},
+		{"zip by language", zipArchive(t, map[string]string{
+			"Movie.2024.English.srt": "Hello",
+			"Movie.2024.Spanish.srt": "Hola",
+			"readme.txt":             "subtitles by",
+		}), "Movie.zip", "es", "Hola", ".srt", nil},
+		{"zip by regional language", zipArchive(t, map[string]string{
+			"Movie.2024.es-ES.srt": "Vale",
+			"Movie.2024.es-MX.srt": "Órale",
+		}), "Movie.zip", "es-419", "Órale", ".srt", nil},
+		{"zip full before forced", zipArchive(t, map[string]string{
+			"Movie.2024.spa.forced.srt": "forced",
+			"Movie.2024.spa.srt":        "full",
+		}), "Movie.zip", "es", "full", ".srt", nil},
+		{"zip without languages", zipArchive(t, map[string]string{
+			"Subs/Movie.2024.ssa": "Hola",
+			"Movie.2024.nfo":      "info",
+		}), "Movie.zip", "es", "Hola", ".ssa", nil},
+		{"zip of another language", zipArchive(t, map[string]string{
+			"Movie.2024.English.srt": "Hello",
+		}), "Movie.zip", "es", "", "", ErrNoSubtitle},
+		{"zip without subtitles", zipArchive(t, map[string]string{
+			"readme.txt": "nothing here",
+		}), "Movie.zip", "es", "", "", ErrNoSubtitle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, ext, err := Extract(tt.data, tt.filename, tt.language)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Extract() error = %v, want %v", err, tt.err)
+			}
+			if string(content) != tt.content || ext != tt.ext {
+				t.Errorf("Extract() = %q, %q, want %q, %q", content, ext, tt.content, tt.ext)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlace(t *testing.T) {
+	dir := t.TempDir()
+	folder := filepath.Join(dir, "Movie.2024.1080p")
+	writeFile(t, filepath.Join(folder, "Movie.2024.1080p.mkv"), 2048)
+	writeFile(t, filepath.Join(folder, "Sample", "sample.mkv"), 512)
+	writeFile(t, filepath.Join(folder, "Movie.2024.1080p.nfo"), 4096)
+	single := filepath.Join(dir, "Other.2023.mp4")
+	writeFile(t, single, 1024)
+	empty := filepath.Join(dir, "Empty")
+	writeFile(t, filepath.Join(empty, "readme.txt"), 10)
+
+	tests := []struct {
+		name        string
+		contentPath string
+		want        string
+		err         error
+	}{
+		{"folder", folder, filepath.Join(folder, "Movie.2024.1080p.es-419.srt"), nil},
+		{"single file", single, filepath.Join(dir, "Other.2023.es-419.srt"), nil},
+		{"no video", empty, "", ErrNoVideo},
+		{"not a video", filepath.Join(empty, "readme.txt"), "", ErrNoVideo},
+		{"missing", filepath.Join(dir, "Missing"), "", os.ErrNotExist},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Place(tt.contentPath, []byte("Hola"), ".srt", "es-419")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Place() error = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Fatalf("Place() = %q, want %q", got, tt.want)
+			}
+			if tt.err != nil {
+				return
+			}
+			if content, err := os.ReadFile(got); err != nil || string(content) != "Hola" {
+				t.Errorf("placed subtitle = %q, %v", content, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package tracker
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/processor-films/internal/config"
+)
+
+type DownloadTracker interface {
+	TrackDownloads(ctx context.Context) error
+}
+
+// Tracker polls the download client every config.DownloadPollInterval.
+type Tracker struct {
+	config    *config.Config
+	logger    *zerolog.Logger
+	processor DownloadTracker
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+func New(config *config.Config, logger *zerolog.Logger, processor DownloadTracker) *Tracker {
+	return &Tracker{
+		config:    config,
+		logger:    logger,
+		processor: processor,
+	}
+}
+
+// Start polls in background, nothing is done when the poll interval is zero.
+func (t *Tracker) Start() {
+	if t.config.DownloadPollInterval <= 0 {
+		t.logger.Info().Msg("download tracking is disabled")
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	t.done = make(chan struct{})
+	go func() {
+		defer close(t.done)
+		ticker := time.NewTicker(t.config.DownloadPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := t.processor.TrackDownloads(ctx); err != nil {
+					t.logger.Err(err).Msg("error while tracking downloads")
+				}
+			}
+		}
+	}()
+	t.logger.Info().Msgf("tracking downloads every %s", t.config.DownloadPollInterval)
+}
+
+func (t *Tracker) Stop() {
+	if t.cancel == nil {
+		return
+	}
+	t.cancel()
+	<-t.done
+}
@@ -2,7 +2,10 @@ package webserver
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	ginlogger "github.com/gin-contrib/logger"
 	"github.com/gin-gonic/gin"
@@ -10,10 +13,32 @@ import (
 	"github.com/xochilpili/processor-films/internal/config"
 	"github.com/xochilpili/processor-films/internal/models"
 	"github.com/xochilpili/processor-films/internal/processor"
+	"github.com/xochilpili/processor-films/internal/scheduler"
+	"github.com/xochilpili/processor-films/internal/tracker"
 )
 
 type Processor interface {
-	Process(ctx context.Context, opType models.OperationType, provider string) error
+	Connect() error
+	Close() error
+	Run(ctx context.Context, opType models.OperationType, opts models.RunOptions) (*models.Job, error)
+	GetJobs(ctx context.Context, limit int) ([]models.Job, error)
+	GetJob(ctx context.Context, id int) (*models.Job, error)
+	GetFilmDownloads(ctx context.Context, filmId int, opType *models.OperationType) ([]models.FilmDownload, error)
+	MetadataCacheStats() models.CacheStats
+	GetGroupAliases() []models.GroupAlias
+	AddGroupAlias(ctx context.Context, alias models.GroupAlias) (*models.GroupAlias, error)
+	GetFilmHistory(ctx context.Context, filmId int, opType *models.OperationType) ([]models.FilmProcessAttempt, error)
+}
+
+type Scheduler interface {
+	Start() error
+	Stop()
+	Schedules() []models.Schedule
+}
+
+type Tracker interface {
+	Start()
+	Stop()
 }
 
 type WebServer struct {
@@ -22,6 +47,8 @@ type WebServer struct {
 	Web       *http.Server
 	ginger    *gin.Engine
 	processor Processor
+	scheduler Scheduler
+	tracker   Tracker
 }
 
 func New(config *config.Config, logger *zerolog.Logger) *WebServer {
@@ -44,6 +71,8 @@ func New(config *config.Config, logger *zerolog.Logger) *WebServer {
 		Web:       httpSrv,
 		ginger:    ginger,
 		processor: processor,
+		scheduler: scheduler.New(config, logger, processor),
+		tracker:   tracker.New(config, logger, processor),
 	}
 
 	srv.loadRoutes()
@@ -51,18 +80,200 @@ func New(config *config.Config, logger *zerolog.Logger) *WebServer {
 	return srv
 }
 
+func (w *WebServer) Connect() error {
+	return w.processor.Connect()
+}
+
+func (w *WebServer) StartScheduler() error {
+	return w.scheduler.Start()
+}
+
+func (w *WebServer) StartTracker() {
+	w.tracker.Start()
+}
+
+func (w *WebServer) Close() error {
+	w.scheduler.Stop()
+	w.tracker.Stop()
+	return w.processor.Close()
+}
+
 func (w *WebServer) pingHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, &gin.H{"messasge": "pong"})
 }
 
 func (w *WebServer) festivalHandler(c *gin.Context) {
-	go w.processor.Process(context.Background(), models.FESTIVALS, "all")
-	c.JSON(http.StatusOK, &gin.H{"message": "ok"})
+	w.runHandler(c, models.FESTIVALS)
 }
 
 func (w *WebServer) popularHandler(c *gin.Context) {
-	go w.processor.Process(context.Background(), models.POPULAR, "all")
-	c.JSON(http.StatusOK, &gin.H{"message": "ok"})
+	w.runHandler(c, models.POPULAR)
+}
+
+func (w *WebServer) runHandler(c *gin.Context, opType models.OperationType) {
+	wait, err := strconv.ParseBool(c.DefaultQuery("wait", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "invalid wait value"})
+		return
+	}
+	batchSize, err := strconv.Atoi(c.DefaultQuery("batch_size", "0"))
+	if err != nil || batchSize < 0 {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "invalid batch_size"})
+		return
+	}
+	maxFilms, err := strconv.Atoi(c.DefaultQuery("max_films", "0"))
+	if err != nil || maxFilms < 0 {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "invalid max_films"})
+		return
+	}
+	var resolutions []string
+	if value := c.Query("resolutions"); value != "" {
+		for _, resolution := range strings.Split(value, ",") {
+			if resolution = strings.TrimSpace(resolution); resolution != "" {
+				resolutions = append(resolutions, resolution)
+			}
+		}
+	}
+	opts := models.RunOptions{
+		Provider:    "all",
+		Wait:        wait,
+		BatchSize:   batchSize,
+		MaxFilms:    maxFilms,
+		Profile:     c.Query("profile"),
+		Resolutions: resolutions,
+	}
+	job, err := w.processor.Run(c.Request.Context(), opType, opts)
+	if errors.Is(err, processor.ErrRunInProgress) {
+		c.JSON(http.StatusConflict, &gin.H{"message": err.Error()})
+		return
+	}
+	if errors.Is(err, processor.ErrUnknownProfile) {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &gin.H{"message": "error while starting job", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "job": job})
+}
+
+func (w *WebServer) jobsHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "invalid limit"})
+		return
+	}
+	jobs, err := w.processor.GetJobs(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &gin.H{"message": "error while fetching jobs", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "total": len(jobs), "data": jobs})
+}
+
+func (w *WebServer) jobHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "invalid job id"})
+		return
+	}
+	job, err := w.processor.GetJob(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &gin.H{"message": "error while fetching job", "error": err.Error()})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, &gin.H{"message": "job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": job})
+}
+
+func (w *WebServer) schedulesHandler(c *gin.Context) {
+	schedules := w.scheduler.Schedules()
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "total": len(schedules), "data": schedules})
+}
+
+// parseFilmQuery returns the film id and the optional type filter of the film routes, invalid values
+// are answered with 400 and false is returned.
+func parseFilmQuery(c *gin.Context) (int, *models.OperationType, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "invalid film id"})
+		return 0, nil, false
+	}
+	var opType *models.OperationType
+	if name := c.Query("type"); name != "" {
+		parsed, ok := models.ParseOperationType(name)
+		if !ok {
+			c.JSON(http.StatusBadRequest, &gin.H{"message": "invalid type"})
+			return 0, nil, false
+		}
+		opType = &parsed
+	}
+	return id, opType, true
+}
+
+func (w *WebServer) filmDownloadHandler(c *gin.Context) {
+	id, opType, ok := parseFilmQuery(c)
+	if !ok {
+		return
+	}
+	downloads, err := w.processor.GetFilmDownloads(c.Request.Context(), id, opType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &gin.H{"message": "error while fetching downloads", "error": err.Error()})
+		return
+	}
+	if len(downloads) == 0 {
+		c.JSON(http.StatusNotFound, &gin.H{"message": "download not found"})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "total": len(downloads), "data": downloads})
+}
+
+func (w *WebServer) filmHistoryHandler(c *gin.Context) {
+	id, opType, ok := parseFilmQuery(c)
+	if !ok {
+		return
+	}
+	attempts, err := w.processor.GetFilmHistory(c.Request.Context(), id, opType)
+	if errors.Is(err, processor.ErrFilmNotFound) {
+		c.JSON(http.StatusNotFound, &gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &gin.H{"message": "error while fetching history", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "total": len(attempts), "data": attempts})
+}
+
+func (w *WebServer) metadataCacheHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": w.processor.MetadataCacheStats()})
+}
+
+func (w *WebServer) groupAliasesHandler(c *gin.Context) {
+	aliases := w.processor.GetGroupAliases()
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "total": len(aliases), "data": aliases})
+}
+
+func (w *WebServer) addGroupAliasHandler(c *gin.Context) {
+	var alias models.GroupAlias
+	if err := c.ShouldBindJSON(&alias); err != nil {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": "invalid group alias", "error": err.Error()})
+		return
+	}
+	saved, err := w.processor.AddGroupAlias(c.Request.Context(), alias)
+	if errors.Is(err, processor.ErrInvalidGroupAlias) {
+		c.JSON(http.StatusBadRequest, &gin.H{"message": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, &gin.H{"message": "error while saving group alias", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, &gin.H{"message": "ok", "data": saved})
 }
 
 func (w *WebServer) loadRoutes() {
@@ -73,4 +284,21 @@ func (w *WebServer) loadRoutes() {
 		process.GET("/festivals", w.festivalHandler)
 		process.GET("/popular", w.popularHandler)
 	}
+	jobs := w.ginger.Group("/jobs")
+	{
+		jobs.GET("", w.jobsHandler)
+		jobs.GET("/:id", w.jobHandler)
+	}
+	api.GET("/schedules", w.schedulesHandler)
+	api.GET("/metrics/metadata-cache", w.metadataCacheHandler)
+	aliases := w.ginger.Group("/groups/aliases")
+	{
+		aliases.GET("", w.groupAliasesHandler)
+		aliases.POST("", w.addGroupAliasHandler)
+	}
+	films := w.ginger.Group("/films")
+	{
+		films.GET("/:id/download", w.filmDownloadHandler)
+		films.GET("/:id/history", w.filmHistoryHandler)
+	}
 }
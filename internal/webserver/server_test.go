@@ -0,0 +1,82 @@
+package webserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/xochilpili/processor-films/internal/config"
+	"github.com/xochilpili/processor-films/internal/models"
+	"github.com/xochilpili/processor-films/internal/processor"
+)
+
+// stubProcessor answers Run with err and records the options, other methods panic.
+type stubProcessor struct {
+	Processor
+	err  error
+	opts *models.RunOptions
+}
+
+func (s *stubProcessor) Run(ctx context.Context, opType models.OperationType, opts models.RunOptions) (*models.Job, error) {
+	s.opts = &opts
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &models.Job{Id: 1, OperationType: opType.String(), Status: models.JOB_RUNNING}, nil
+}
+
+func newTestServer(p Processor) *WebServer {
+	gin.SetMode(gin.TestMode)
+	logger := zerolog.Nop()
+	w := &WebServer{
+		config:    &config.Config{},
+		logger:    &logger,
+		ginger:    gin.New(),
+		processor: p,
+	}
+	w.loadRoutes()
+	return w
+}
+
+func TestRunHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		err    error
+		status int
+		wait   bool
+	}{
+		{"started", "/process/festivals", nil, http.StatusOK, false},
+		{"queued", "/process/popular?wait=true", nil, http.StatusOK, true},
+		{"in progress", "/process/festivals", processor.ErrRunInProgress, http.StatusConflict, false},
+		{"batch size", "/process/festivals?batch_size=5&max_films=20", nil, http.StatusOK, false},
+		{"invalid batch size", "/process/festivals?batch_size=abc", nil, http.StatusBadRequest, false},
+		{"negative batch size", "/process/festivals?batch_size=-1", nil, http.StatusBadRequest, false},
+		{"invalid max films", "/process/popular?max_films=abc", nil, http.StatusBadRequest, false},
+		{"negative max films", "/process/popular?max_films=-5", nil, http.StatusBadRequest, false},
+		{"unknown profile", "/process/festivals?profile=nope", processor.ErrUnknownProfile, http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &stubProcessor{err: tt.err}
+			w := newTestServer(p)
+			rec := httptest.NewRecorder()
+			w.ginger.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if rec.Code != tt.status {
+				t.Errorf("GET %s = %d, want %d: %s", tt.url, rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.status == http.StatusBadRequest && tt.err == nil {
+				if p.opts != nil {
+					t.Errorf("GET %s started a run", tt.url)
+				}
+				return
+			}
+			if p.opts.Wait != tt.wait {
+				t.Errorf("GET %s wait = %v, want %v", tt.url, p.opts.Wait, tt.wait)
+			}
+		})
+	}
+}